@@ -0,0 +1,371 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"osearch/pkg/osearch"
+)
+
+// osearch record [--vault name] path
+//
+// run from the workflow after a note is opened, with the path variable
+// of the chosen result
+func recordCommand(args []string) {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	vaultName := flags.String("vault", "", "name of the vault the note is in")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fail(osearch.ExitUsage, "Usage: %s record [--vault vaultname] path", os.Args[0])
+	}
+	if len(*vaultName) == 0 {
+		*vaultName, _ = obsidianDefaults()
+	}
+	err := osearch.RecordVisit(strings.Join(flags.Args(), " "), *vaultName)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch edit [--config file] [--editor app] fullpath
+//
+// run from the workflow to open the chosen note in an editor rather than
+// Obsidian, with the fullpath variable of the result
+func editCommand(args []string) {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	editor := flags.String("editor", "", "the app or command to open the note in (default the config's editor, $VISUAL or $EDITOR)")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fail(osearch.ExitUsage, "Usage: %s edit [--editor app] fullpath", os.Args[0])
+	}
+	config := loadConfig(osearch.ExpandHome(*configFile))
+	if len(*editor) > 0 {
+		config.Editor = *editor
+	}
+	err := osearch.OpenInEditor(strings.Join(flags.Args(), " "), config)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch contents [--line n] fullpath
+//
+// run from the workflow to print the chosen note's text for the clipboard,
+// or with the line variable of a --grep result, the section it's in
+func contentsCommand(args []string) {
+	flags := flag.NewFlagSet("contents", flag.ExitOnError)
+	line := flags.Int("line", 0, "print only the section this line of the note is in")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fail(osearch.ExitUsage, "Usage: %s contents [--line n] fullpath", os.Args[0])
+	}
+	text, err := osearch.NoteContents(osearch.ExpandHome(strings.Join(flags.Args(), " ")), *line)
+	if err != nil {
+		failOn(err)
+	}
+	fmt.Print(text)
+}
+
+// osearch pin [--config file] [--remove] path
+func pinCommand(args []string) {
+	flags := flag.NewFlagSet("pin", flag.ExitOnError)
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	remove := flags.Bool("remove", false, "unpin the note instead")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fail(osearch.ExitUsage, "Usage: %s pin [--remove] path", os.Args[0])
+	}
+	err := osearch.Pin(osearch.ExpandHome(*configFile), strings.Join(flags.Args(), " "), *remove)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch ignore [--config file] [--remove] path
+func ignoreCommand(args []string) {
+	flags := flag.NewFlagSet("ignore", flag.ExitOnError)
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	remove := flags.Bool("remove", false, "stop ignoring the note or folder")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fail(osearch.ExitUsage, "Usage: %s ignore [--remove] path", os.Args[0])
+	}
+	err := osearch.Ignore(osearch.ExpandHome(*configFile), strings.Join(flags.Args(), " "), *remove)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch stats [--vault name] [--path dir] [--format plain] [--top n]
+func statsCommand(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	vaultName := flags.String("vault", "", "name of the vault")
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	format := flags.String("format", osearch.FormatAlfred, "write the stats for alfred (or another --format), or as plain text")
+	top := flags.Int("top", 10, "how many of the largest notes, most linked notes and tags to show")
+	flags.Parse(args)
+
+	config := loadConfig(osearch.ExpandHome(*configFile))
+	defaultVault, defaultPath := obsidianDefaults()
+	if len(*vaultName) == 0 {
+		*vaultName = defaultVault
+	}
+	if len(*vaultPath) == 0 {
+		*vaultPath = defaultPath
+	}
+
+	directory := osearch.ExpandHome(*vaultPath)
+	stats, err := osearch.Stats(directory, config, *top)
+	if err != nil {
+		failOn(err)
+	}
+	if *format == osearch.FormatPlain {
+		err = stats.WriteText(os.Stdout)
+	} else {
+		err = osearch.WriteResults(os.Stdout, stats.Results(directory, *vaultName, config), *format)
+	}
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch graph [--path dir] [--config file] [--format dot|json]
+func graphCommand(args []string) {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	format := flags.String("format", osearch.FormatDot, "write the graph as dot or json")
+	flags.Parse(args)
+
+	config := loadConfig(osearch.ExpandHome(*configFile))
+	if len(*vaultPath) == 0 {
+		_, *vaultPath = obsidianDefaults()
+	}
+
+	graph, err := osearch.LinkGraph(osearch.ExpandHome(*vaultPath), config)
+	if err != nil {
+		failOn(err)
+	}
+	err = graph.Write(os.Stdout, *format)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch daily [--vault name] [--path dir] [--format f] [today | yesterday | 2024-01-31 | 3d]
+func dailyCommand(args []string) {
+	flags := flag.NewFlagSet("daily", flag.ExitOnError)
+	vaultName := flags.String("vault", "", "name of the vault")
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	format := flags.String("format", osearch.FormatAlfred, "write the result for alfred, or another --format")
+	flags.Parse(args)
+
+	config := loadConfig(osearch.ExpandHome(*configFile))
+	if len(*vaultName) == 0 || len(*vaultPath) == 0 {
+		defaultVault, defaultPath := obsidianDefaults()
+		if len(*vaultName) == 0 {
+			*vaultName = defaultVault
+		}
+		if len(*vaultPath) == 0 {
+			*vaultPath = defaultPath
+		}
+	}
+
+	day, err := osearch.ParseDay(strings.Join(flags.Args(), " "), time.Now())
+	if err != nil {
+		failOn(err)
+	}
+	results := osearch.DailyNote(osearch.ExpandHome(*vaultPath), *vaultName, config, day)
+	err = osearch.WriteResults(os.Stdout, results, *format)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch index [--path dir] [--every seconds]
+//
+// brings the index backend's index up to date, and with an embedding
+// command, the embeddings --semantic compares, once or over and over
+func indexCommand(args []string) {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	every := flags.Int("every", 0, "keep going, updating the index this many seconds apart")
+	flags.Parse(args)
+
+	if len(*vaultPath) == 0 {
+		_, *vaultPath = obsidianDefaults()
+	}
+	directory := osearch.ExpandHome(*vaultPath)
+	for {
+		// read each time round so config changes are picked up
+		config := loadConfig(osearch.ExpandHome(*configFile))
+		err := osearch.UpdateIndex(directory, config)
+		if err == nil && len(config.EmbedCommand) > 0 {
+			err = osearch.UpdateEmbeddings(directory, config)
+		}
+		if err != nil {
+			failOn(err)
+		}
+		if *every <= 0 {
+			return
+		}
+		time.Sleep(time.Duration(*every) * time.Second)
+	}
+}
+
+// osearch service install [--path dir] [--data dir] [--every seconds]
+// osearch service uninstall
+func serviceCommand(args []string) {
+	if len(args) < 1 || (args[0] != "install" && args[0] != "uninstall") {
+		fail(osearch.ExitUsage, "Usage: %s service install [--path vaultpath] [--data dir] [--every seconds] | uninstall", os.Args[0])
+	}
+	if args[0] == "uninstall" {
+		err := osearch.UninstallService()
+		if err != nil {
+			failOn(err)
+		}
+		return
+	}
+
+	flags := flag.NewFlagSet("service install", flag.ExitOnError)
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	dataFolder := flags.String("data", "", "the Alfred workflow's data folder, where searches look for the index")
+	every := flags.Int("every", 60, "update the index this many seconds apart")
+	flags.Parse(args[1:])
+
+	if len(*vaultPath) == 0 {
+		_, *vaultPath = obsidianDefaults()
+	}
+	err := osearch.InstallService(*vaultPath, *dataFolder, *every)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// osearch bench [--vault name] [--path dir] [--mode grep] [--runs n] [--backends native,index] query
+func benchCommand(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	vaultName := flags.String("vault", "", "name of the vault")
+	vaultPath := flags.String("path", "", "path to the vault directory")
+	configFile := flags.String("config", osearch.DefaultConfigFile(), "path to osearch config file")
+	mode := flags.String("mode", osearch.ModeName, "the kind of search: name, fuzzy, grep, frontmatter, both or list")
+	runs := flags.Int("runs", 10, "how many times to run the search on each backend")
+	backends := flags.String("backends", "", "the backends to compare, separated by commas (default every one available)")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 && *mode != osearch.ModeList {
+		fail(osearch.ExitUsage, "Usage: %s bench [--mode grep] [--runs n] [--backends fd,native,index] query", os.Args[0])
+	}
+	config := loadConfig(osearch.ExpandHome(*configFile))
+	config.PerFile = 1
+	if len(*vaultName) == 0 || len(*vaultPath) == 0 {
+		defaultVault, defaultPath := obsidianDefaults()
+		if len(*vaultName) == 0 {
+			*vaultName = defaultVault
+		}
+		if len(*vaultPath) == 0 {
+			*vaultPath = defaultPath
+		}
+	}
+	var names []string
+	if len(*backends) > 0 {
+		names = strings.Split(*backends, ",")
+	}
+
+	results := osearch.Bench(osearch.Options{
+		Mode:         *mode,
+		Query:        strings.Join(flags.Args(), " "),
+		Vault:        *vaultName,
+		Path:         *vaultPath,
+		Config:       config,
+		Sort:         osearch.SortRelevance,
+		CacheSeconds: -1,
+	}, names, *runs)
+	err := osearch.WriteBench(os.Stdout, results)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// the subcommands main runs instead of a search
+var subcommands = []string{"record", "pin", "ignore", "stats", "graph", "daily", "index", "service", "bench", "update", "package", "edit", "contents", "completion"}
+
+// osearch completion bash|zsh|fish
+//
+// the completion scripts also run osearch completion vaults and osearch
+// completion searches to list the names they offer
+func completionCommand(args []string, searchFlags *flag.FlagSet) {
+	if len(args) < 1 {
+		fail(osearch.ExitUsage, "Usage: %s completion bash|zsh|fish", os.Args[0])
+	}
+	switch args[0] {
+	case "vaults":
+		for _, name := range osearch.VaultNames(osearch.ExpandHome(osearch.ObsidianConfigFile)) {
+			fmt.Println(name)
+		}
+		return
+	case "searches":
+		config := loadConfig(osearch.DefaultConfigFile())
+		for _, name := range osearch.SavedSearchNames(config) {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	var flags []osearch.CompletionFlag
+	searchFlags.VisitAll(func(f *flag.Flag) {
+		boolean, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, osearch.CompletionFlag{Name: f.Name, Usage: f.Usage, TakesValue: !ok || !boolean.IsBoolFlag()})
+	})
+	err := osearch.WriteCompletion(os.Stdout, args[0], subcommands, flags)
+	if err != nil {
+		fail(osearch.ExitUsage, "%s", err)
+	}
+}
+
+// osearch update [--check] [--force]
+func updateCommand(args []string) {
+	flags := flag.NewFlagSet("update", flag.ExitOnError)
+	check := flags.Bool("check", false, "only say whether there's a newer release")
+	force := flags.Bool("force", false, "install the latest release even if it isn't newer")
+	flags.Parse(args)
+
+	message, err := osearch.Update(*check, *force)
+	if err != nil {
+		failOn(err)
+	}
+	fmt.Println(message)
+}
+
+// osearch package [--out file] [--binary osearch] [--icon icon.png]
+func packageCommand(args []string) {
+	flags := flag.NewFlagSet("package", flag.ExitOnError)
+	out := flags.String("out", "Obsidian Search.alfredworkflow", "where to write the workflow")
+	binary := flags.String("binary", "", "the osearch binary the workflow runs (default this one)")
+	icon := flags.String("icon", "", "a PNG icon for the workflow (default Obsidian's)")
+	flags.Parse(args)
+
+	if len(*binary) == 0 {
+		executable, err := os.Executable()
+		if err != nil {
+			failOn(err)
+		}
+		*binary = executable
+	}
+	err := osearch.PackageWorkflow(*out, *binary, *icon)
+	if err != nil {
+		failOn(err)
+	}
+}
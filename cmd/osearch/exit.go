@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"osearch/pkg/osearch"
+)
+
+var exitKinds = map[osearch.ExitCode]string{
+	osearch.ExitFailed:    "failed",
+	osearch.ExitUsage:     "usage",
+	osearch.ExitConfig:    "config",
+	osearch.ExitVault:     "vault",
+	osearch.ExitBackend:   "backend",
+	osearch.ExitNoResults: "no-results",
+}
+
+// errorsJson has failures written to stdout as JSON instead of logged
+var errorsJson bool
+
+// jsonError is what --errors-json writes when osearch fails
+type jsonError struct {
+	Error struct {
+		Kind    string `json:"kind"`
+		Exit    int    `json:"exit"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// give up with code, logging the message or writing it as JSON
+func fail(code osearch.ExitCode, format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	osearch.Debug("error", "exit", int(code), "message", message)
+	if errorsJson {
+		var failure jsonError
+		failure.Error.Kind = exitKinds[code]
+		failure.Error.Exit = int(code)
+		failure.Error.Message = message
+		json.NewEncoder(os.Stdout).Encode(failure)
+	} else {
+		log.Print(message)
+	}
+	os.Exit(int(code))
+}
+
+// give up because of err, with the exit code that suits it
+func failOn(err error) {
+	fail(osearch.ExitCodeFor(err), "%s", err)
+}
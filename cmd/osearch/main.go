@@ -0,0 +1,498 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"osearch/pkg/osearch"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "record":
+			recordCommand(os.Args[2:])
+			return
+		case "pin":
+			pinCommand(os.Args[2:])
+			return
+		case "ignore":
+			ignoreCommand(os.Args[2:])
+			return
+		case "stats":
+			statsCommand(os.Args[2:])
+			return
+		case "graph":
+			graphCommand(os.Args[2:])
+			return
+		case "daily":
+			dailyCommand(os.Args[2:])
+			return
+		case "index":
+			indexCommand(os.Args[2:])
+			return
+		case "service":
+			serviceCommand(os.Args[2:])
+			return
+		case "bench":
+			benchCommand(os.Args[2:])
+			return
+		case "update":
+			updateCommand(os.Args[2:])
+			return
+		case "package":
+			packageCommand(os.Args[2:])
+			return
+		case "edit":
+			editCommand(os.Args[2:])
+			return
+		case "contents":
+			contentsCommand(os.Args[2:])
+			return
+		}
+	}
+
+	var grepMode bool
+	var listMode bool
+	var fuzzyMode bool
+	var frontmatterMode bool
+	var bothMode bool
+	var semanticMode bool
+	var templatesMode bool
+	var conflictsMode bool
+	var includeConflicts bool
+	var fallback bool
+	var format string
+	var previewHtml bool
+	var showWordCount bool
+	var showVault bool
+	var icloudDownload bool
+	var editor string
+	var action string
+	var perFile int
+	var dedupe string
+	var contextWords int
+	var markers string
+	var noCode bool
+	var noFrontmatter bool
+	var vaultName string
+	var vaultPath string
+	var from string
+	var vaults string
+	var configFile string
+	var cacheSeconds int
+	var rerunSeconds float64
+	var groupBy string
+	var typos int
+	var stemming bool
+	var regexMode bool
+	var searchFolder string
+	var inFolder string
+	var excludes globList
+	var since string
+	var before string
+	var createdSince string
+	var createdBefore string
+	var sortOrder string
+	var caseSensitive bool
+	var ignoreCase bool
+	var skipKnowledge string
+	var backend string
+	var rgArgs string
+	var fdArgs string
+	var maxDepth int
+	var follow bool
+	var hidden bool
+	var multiline bool
+	var countMode bool
+	var history int
+	var saved bool
+	var debug bool
+	var showVersion bool
+
+	flag.BoolVar(&grepMode, "grep", false, "search file contents")
+	flag.BoolVar(&listMode, "list", false, "list every note for Alfred to filter")
+	flag.BoolVar(&fuzzyMode, "fuzzy", false, "match file names fuzzily")
+	flag.BoolVar(&bothMode, "both", false, "search file names and contents together")
+	flag.BoolVar(&semanticMode, "semantic", false, "find notes close in meaning, using the config's embedCommand")
+	flag.BoolVar(&templatesMode, "templates", false, "list the templates in the templates plugin's folder")
+	flag.BoolVar(&conflictsMode, "conflicts", false, "list the conflicted copies sync tools have made of notes")
+	flag.BoolVar(&includeConflicts, "include-conflicts", false, "show conflicted copies of notes in other searches too")
+	flag.BoolVar(&fallback, "fallback", true, "search contents when no file names match")
+	flag.BoolVar(&frontmatterMode, "frontmatter", false, "search only the frontmatter of notes")
+	flag.BoolVar(&previewHtml, "preview-html", false, "render notes to HTML for Quick Look")
+	flag.IntVar(&perFile, "per-file", 1, "show up to this many matching lines from each note in --grep")
+	flag.StringVar(&dedupe, "dedupe", "", "which matching lines of a note --grep shows: first, best or off for all of them")
+	flag.IntVar(&contextWords, "context", 0, "show this many words either side of a --grep match")
+	flag.StringVar(&markers, "markers", "", "put these either side of a --grep match, e.g. \"» «\"")
+	flag.BoolVar(&showWordCount, "word-count", false, "show how long each note is")
+	flag.BoolVar(&showVault, "show-vault", false, "start subtitles with the vault's name")
+	flag.StringVar(&editor, "editor", "", "open notes outside Obsidian in this app or command (default $VISUAL or $EDITOR)")
+	flag.StringVar(&action, "action", "", "what picking a result does: obsidian, editor to open the note in the editor, contents to copy its text, or wikilink or markdown to copy a link to it")
+	flag.BoolVar(&icloudDownload, "icloud-download", false, "have iCloud Drive download the evicted notes a search shows")
+	flag.StringVar(&format, "format", osearch.FormatAlfred, "write results for alfred, raycast, launchbar, lua (Hammerspoon), or as json, jsonl, plain or tsv")
+	flag.StringVar(&vaultName, "vault", "", "name of vault to search")
+	flag.StringVar(&vaultPath, "path", "", "path to vault directory")
+	flag.StringVar(&vaults, "vaults", "", "search these vaults Obsidian knows, separated by commas, or all of them")
+	flag.StringVar(&from, "from", "", "search the vault this file or folder is in (. for the current folder)")
+	flag.IntVar(&cacheSeconds, "cache", -1, "seconds Alfred may cache results for (0 disables, default depends on mode)")
+	flag.Float64Var(&rerunSeconds, "rerun", 0, "seconds after which Alfred runs the search again while open")
+	flag.StringVar(&groupBy, "group-by", "", "group results under headers (folder, or vault with --vaults)")
+	flag.StringVar(&skipKnowledge, "skip-knowledge", "auto", "stop Alfred reordering results by what you pick (auto, true, false)")
+	flag.IntVar(&typos, "typos", 0, "words in --grep may have up to this many typos (0-2)")
+	flag.StringVar(&inFolder, "in", "", "only search this folder of the vault (the same as --folder)")
+	flag.Var(&excludes, "exclude", "leave out notes and folders matching this glob, e.g. '*.excalidraw.md' or 'Archive/**'; give it again for more")
+	flag.StringVar(&searchFolder, "folder", "", "only search this folder of the vault, e.g. Work/Projects (the same as --in)")
+	flag.StringVar(&since, "since", "", "only notes modified since a date (2024-01-31) or age (7d)")
+	flag.StringVar(&before, "before", "", "only notes last modified before a date (2024-01-31) or age (7d)")
+	flag.StringVar(&createdSince, "created-since", "", "only notes created since a date (2024-01-31) or age (7d)")
+	flag.StringVar(&createdBefore, "created-before", "", "only notes created before a date (2024-01-31) or age (7d)")
+	flag.StringVar(&sortOrder, "sort", osearch.SortRelevance, "sort results by relevance, modified, created, title or path")
+	flag.BoolVar(&regexMode, "regex", false, "treat the search as a regular expression")
+	flag.BoolVar(&countMode, "count", false, "list notes by how often --grep matches in them")
+	flag.BoolVar(&multiline, "multiline", false, "let --grep matches span lines")
+	flag.BoolVar(&stemming, "stem", false, "match other forms of English words in --grep")
+	flag.BoolVar(&noCode, "no-code", false, "leave matches in fenced code blocks out of --grep")
+	flag.BoolVar(&noFrontmatter, "no-frontmatter", false, "leave matches in frontmatter out of --grep")
+	flag.BoolVar(&saved, "saved", false, "run the config's saved search of this name, or list the saved searches")
+	flag.IntVar(&history, "history", 0, "remember this many searches and show them when the search is empty")
+	flag.StringVar(&backend, "backend", "", "search with fd and rg, native (no external tools) or index")
+	flag.IntVar(&maxDepth, "max-depth", 0, "only look this many folders deep, where 1 is the top of the vault")
+	flag.BoolVar(&follow, "follow", false, "search folders and notes that are symlinks")
+	flag.BoolVar(&hidden, "hidden", false, "search hidden folders and notes too, apart from .obsidian and the like")
+	flag.StringVar(&rgArgs, "rg-args", "", "pass these extra arguments to rg, quoted as in a shell")
+	flag.StringVar(&fdArgs, "fd-args", "", "pass these extra arguments to fd, quoted as in a shell")
+	flag.BoolVar(&caseSensitive, "case-sensitive", false, "always match case")
+	flag.BoolVar(&ignoreCase, "ignore-case", false, "never match case")
+	flag.StringVar(&configFile, "config", osearch.DefaultConfigFile(), "path to osearch config file")
+	flag.BoolVar(&debug, "debug", false, "log what osearch does to debug.log in the data folder")
+	flag.BoolVar(&showVersion, "version", false, "print which version of osearch this is")
+	flag.BoolVar(&errorsJson, "errors-json", false, "write failures to stdout as JSON, and fail when nothing is found")
+
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		completionCommand(os.Args[2:], flag.CommandLine)
+		return
+	}
+
+	flag.Parse()
+
+	if showVersion {
+		fmt.Println(osearch.VersionString())
+		return
+	}
+
+	if debug {
+		err := osearch.EnableDebug()
+		if err != nil {
+			log.Printf("could not start the debug log: %s", err)
+		}
+	}
+
+	config := loadConfig(osearch.ExpandHome(configFile))
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	osearch.Debug("config", "file", configFile, "backend", config.Backend, "case", config.Case, "typos", config.Typos, "fallback", config.Fallback)
+	if setFlags["typos"] {
+		config.Typos = typos
+	}
+	config.Regex = regexMode
+	config.Multiline = multiline
+	config.Count = countMode
+	config.PerFile = perFile
+	if config.PerFile < 1 {
+		config.PerFile = 1
+	}
+	if setFlags["dedupe"] {
+		config.Dedupe = dedupe
+	}
+	switch config.Dedupe {
+	case "", osearch.DedupeFirst, osearch.DedupeBest, osearch.DedupeOff:
+	default:
+		fail(osearch.ExitUsage, "bad --dedupe: %s isn't first, best or off", config.Dedupe)
+	}
+	config.Exclude = excludes
+	if setFlags["history"] {
+		config.History = history
+	}
+	if setFlags["context"] {
+		config.Context = contextWords
+	}
+	if setFlags["fallback"] {
+		config.Fallback = fallback
+	}
+	if setFlags["no-code"] {
+		config.NoCode = noCode
+	}
+	if setFlags["no-frontmatter"] {
+		config.NoFrontmatter = noFrontmatter
+	}
+	if setFlags["markers"] {
+		config.Markers = markers
+	}
+	if setFlags["word-count"] {
+		config.WordCount = showWordCount
+	}
+	if setFlags["show-vault"] {
+		config.ShowVault = showVault
+	}
+	if setFlags["include-conflicts"] {
+		config.IncludeConflicts = includeConflicts
+	}
+	if setFlags["editor"] {
+		config.Editor = editor
+	}
+	if setFlags["action"] {
+		config.Action = action
+	}
+	switch config.Action {
+	case "", osearch.ActionObsidian, osearch.ActionEditor, osearch.ActionContents, osearch.ActionWikilink, osearch.ActionMarkdownLink:
+	default:
+		fail(osearch.ExitUsage, "bad --action: %s isn't obsidian, editor, contents, wikilink or markdown", config.Action)
+	}
+	if setFlags["icloud-download"] {
+		config.ICloudDownload = icloudDownload
+	}
+	if setFlags["stem"] {
+		config.Stem = stemming
+	}
+	if setFlags["backend"] {
+		config.Backend = backend
+		config.Backends = nil
+	}
+	if setFlags["max-depth"] {
+		config.MaxDepth = maxDepth
+	}
+	if setFlags["follow"] {
+		config.Follow = follow
+	}
+	if setFlags["hidden"] {
+		config.Hidden = hidden
+	}
+	if setFlags["rg-args"] {
+		config.RgArgs = splitArgs("rg-args", rgArgs)
+	}
+	if setFlags["fd-args"] {
+		config.FdArgs = splitArgs("fd-args", fdArgs)
+	}
+	if inFolder != "" && searchFolder != "" && inFolder != searchFolder {
+		fail(osearch.ExitUsage, "--in %s and --folder %s name different folders", inFolder, searchFolder)
+	} else if inFolder != "" {
+		searchFolder = inFolder
+	}
+	if caseSensitive && ignoreCase {
+		fail(osearch.ExitUsage, "--case-sensitive and --ignore-case can't both be set")
+	} else if caseSensitive {
+		config.Case = osearch.CaseSensitive
+	} else if ignoreCase {
+		config.Case = osearch.CaseInsensitive
+	}
+
+	if len(from) > 0 {
+		fromVault, fromPath, err := osearch.FindVault(osearch.ExpandHome(from), osearch.ExpandHome(osearch.ObsidianConfigFile))
+		if err != nil {
+			fail(osearch.ExitVault, "%s", err)
+		}
+		if len(vaultName) == 0 {
+			vaultName = fromVault
+		}
+		if len(vaultPath) == 0 {
+			vaultPath = fromPath
+		}
+	}
+
+	var searchVaults []osearch.VaultLocation
+	if len(vaults) > 0 {
+		known, err := osearch.KnownVaults(osearch.ExpandHome(osearch.ObsidianConfigFile))
+		if err != nil {
+			fail(osearch.ExitConfig, "%s", err)
+		}
+		searchVaults, err = osearch.PickVaults(known, strings.Split(vaults, ","))
+		if err != nil {
+			fail(osearch.ExitVault, "%s", err)
+		}
+	} else if len(vaultName) == 0 || len(vaultPath) == 0 {
+		defaultVault, defaultPath := obsidianDefaults()
+		if len(vaultName) == 0 {
+			vaultName = defaultVault
+		}
+		if len(vaultPath) == 0 {
+			vaultPath = defaultPath
+		}
+	}
+
+	osearch.Debug("vault", "name", vaultName, "path", vaultPath, "vaults", vaults)
+	if len(vaultPath) == 0 && len(searchVaults) == 0 {
+		fail(osearch.ExitVault, "no vault given with --path or --from, and none open in Obsidian")
+	}
+
+	var searchTerm string
+	if len(flag.Args()) >= 1 {
+		searchTerm = strings.Join(flag.Args(), " ")
+	} else if !listMode && !templatesMode && !conflictsMode && !saved && config.History <= 0 {
+		fail(osearch.ExitUsage, "Usage: %s [--grep | --both | --frontmatter | --semantic | --templates | --conflicts | --list | --fuzzy] --vault vaultname --path vaultpath searchterm", os.Args[0])
+	}
+
+	mode := osearch.ModeName
+	if listMode {
+		mode = osearch.ModeList
+	} else if templatesMode {
+		mode = osearch.ModeTemplates
+	} else if conflictsMode {
+		mode = osearch.ModeConflicts
+	} else if frontmatterMode {
+		mode = osearch.ModeFrontmatter
+	} else if grepMode {
+		mode = osearch.ModeGrep
+	} else if semanticMode {
+		mode = osearch.ModeSemantic
+	} else if bothMode {
+		mode = osearch.ModeBoth
+	} else if fuzzyMode {
+		mode = osearch.ModeFuzzy
+	}
+
+	options := osearch.Options{
+		Mode:           mode,
+		Query:          searchTerm,
+		Vault:          vaultName,
+		Path:           vaultPath,
+		InFolder:       searchFolder,
+		Config:         config,
+		ModifiedSince:  timeFlag("since", since),
+		ModifiedBefore: timeFlag("before", before),
+		CreatedSince:   timeFlag("created-since", createdSince),
+		CreatedBefore:  timeFlag("created-before", createdBefore),
+		Sort:           sortOrder,
+		GroupBy:        groupBy,
+		SkipKnowledge:  skipKnowledge,
+		CacheSeconds:   cacheSeconds,
+		RerunSeconds:   rerunSeconds,
+		PreviewHtml:    previewHtml,
+		Saved:          saved,
+	}
+	streamed := 0
+	if format == osearch.FormatJsonLines {
+		// each line goes out as soon as its result is found
+		options.Found = func(result osearch.AlfredResult) error {
+			streamed++
+			return osearch.WriteJsonLine(os.Stdout, result)
+		}
+	}
+	var results osearch.AlfredResults
+	var err error
+	if len(searchVaults) > 0 {
+		results, err = osearch.SearchVaults(options, searchVaults)
+	} else {
+		results, err = osearch.Search(options)
+	}
+	if err != nil {
+		failOn(err)
+	}
+	if errorsJson && len(results.Items) == 0 && streamed == 0 {
+		fail(osearch.ExitNoResults, "nothing matches %s", searchTerm)
+	}
+
+	err = osearch.WriteResults(os.Stdout, results, format)
+	if err != nil {
+		failOn(err)
+	}
+}
+
+// the config file's settings over the defaults, or failing that, exit
+func loadConfig(configFile string) osearch.Config {
+	config, err := osearch.LoadConfig(configFile)
+	if err != nil {
+		failOn(err)
+	}
+	return config
+}
+
+// the name and folder of the vault open in Obsidian, or failing to read
+// Obsidian's config, exit
+func obsidianDefaults() (string, string) {
+	vault, path, err := osearch.GetDefaults(osearch.ExpandHome(osearch.ObsidianConfigFile))
+	if err != nil {
+		failOn(err)
+	}
+	return vault, path
+}
+
+// the time given to a --since or --before style flag, if any
+func timeFlag(name string, value string) time.Time {
+	if len(value) == 0 {
+		return time.Time{}
+	}
+	t, err := osearch.ParseTimeBound(value, time.Now())
+	if err != nil {
+		fail(osearch.ExitUsage, "bad --%s: %s", name, err)
+	}
+	return t
+}
+
+// the words of value, quoted the way a shell quotes them: 'single quotes'
+// keep everything, "double quotes" and backslashes keep the next character
+func splitArgs(name string, value string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		fail(osearch.ExitUsage, "bad --%s: unfinished quote in %s", name, value)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// the values of a flag given any number of times
+type globList []string
+
+func (globs *globList) String() string {
+	return strings.Join(*globs, ",")
+}
+
+func (globs *globList) Set(value string) error {
+	*globs = append(*globs, value)
+	return nil
+}
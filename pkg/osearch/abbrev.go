@@ -0,0 +1,66 @@
+package osearch
+
+import "strings"
+
+// how much an abbreviation match adds to a fuzzy score, per query character
+const abbreviationBonus = 20
+
+// the first letter of each word, "ADR" for "Architecture Decision Record"
+func initials(title string) string {
+	var letters []rune
+	for _, word := range tokenize(title) {
+		letters = append(letters, []rune(word)[0])
+	}
+	return string(letters)
+}
+
+// whether abbreviation starts like word and the rest of its letters follow
+// in order, as "mtg" does for "meeting"
+func abbreviates(abbreviation string, word string) bool {
+	short := []rune(strings.ToLower(abbreviation))
+	long := []rune(strings.ToLower(word))
+	if len(short) == 0 || len(long) == 0 || short[0] != long[0] {
+		return false
+	}
+	index := 1
+	for _, letter := range long[1:] {
+		if index < len(short) && short[index] == letter {
+			index++
+		}
+	}
+	return index == len(short)
+}
+
+// abbreviationScore rewards queries that read as the title's initials, or
+// whose words each abbreviate a word of the title in order, the way app
+// launchers match "ADR" to "Architecture Decision Record" and "mtg notes q3"
+// to "Meeting Notes Q3"
+func abbreviationScore(query string, title string) int {
+	query = foldDiacritics(query)
+	title = foldDiacritics(title)
+	words := tokenize(title)
+	if len(words) == 0 {
+		return 0
+	}
+
+	compact := strings.ToLower(strings.Replace(query, " ", "", -1))
+	if len(compact) > 1 && strings.HasPrefix(strings.ToLower(initials(title)), compact) {
+		return abbreviationBonus * len([]rune(compact))
+	}
+
+	queryWords := strings.Fields(query)
+	if len(queryWords) == 0 {
+		return 0
+	}
+	next := 0
+	for _, queryWord := range queryWords {
+		for next < len(words) && !abbreviates(queryWord, words[next]) {
+			next++
+		}
+		if next == len(words) {
+			return 0
+		}
+		next++
+	}
+	return abbreviationBonus * len([]rune(compact))
+}
@@ -0,0 +1,123 @@
+package osearch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// the backends a search can run on
+const (
+	// fd and rg, the fastest on a vault searched now and then
+	BackendExternal = "fd"
+	// walks and reads the vault itself, for machines without fd and rg
+	BackendNative = "native"
+	// keeps the vault's text in an index that's brought up to date on each
+	// search, so only changed notes are read again
+	BackendIndex = "index"
+)
+
+// SearchBackend does the finding for the search modes, leaving them to rank
+// and format what it finds. Patterns are regular expressions in the syntax
+// rg and Go share, with any case insensitivity built in as (?i:...). Paths
+// are relative to the vault's folder, config.directory, and only files
+// under config.Folders (or anywhere, if there are none) count.
+type SearchBackend interface {
+	// FindFiles lists the files whose names match pattern, or every file if
+	// pattern is empty
+	FindFiles(pattern string, config Config) ([]string, error)
+	// FilesContaining lists the files with a line that matches pattern
+	FilesContaining(pattern string, config Config) (map[string]bool, error)
+	// GrepContent finds every line that matches pattern
+	GrepContent(pattern string, config Config) ([]LineMatch, error)
+}
+
+// LineMatch is a line a backend found, with where the first match in it is
+type LineMatch struct {
+	Path   string
+	Number int
+	Text   string
+	// byte offsets of the match in Text
+	Start int
+	End   int
+}
+
+// the name of the backend config asks for in mode
+func backendName(mode string, config Config) string {
+	if perMode, ok := config.Backends[mode]; ok {
+		return perMode
+	}
+	if len(config.Backend) == 0 {
+		return BackendExternal
+	}
+	return config.Backend
+}
+
+// the backend config asks for in mode, from its "backends" for that mode
+// or else its "backend"
+func backendFor(mode string, config Config) (SearchBackend, error) {
+	name := backendName(mode, config)
+	switch name {
+	case BackendExternal:
+		return externalBackend{}, nil
+	case BackendNative:
+		return &nativeBackend{}, nil
+	case BackendIndex:
+		return &indexBackend{}, nil
+	}
+	return nil, exitError(ExitBackend, "there's no %s backend", name)
+}
+
+// the backend a search was given, or fd and rg if it wasn't given one
+func (config Config) searchBackend() SearchBackend {
+	if config.backend == nil {
+		return externalBackend{}
+	}
+	return config.backend
+}
+
+// where path, relative to the vault, is from the working directory
+func (config Config) vaultPath(path string) string {
+	return filepath.Join(config.directory, path)
+}
+
+// how many folders deep path is, counting the file itself, so a note at
+// the top of the vault is at depth 1
+func pathDepth(path string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(path)), "/") + 1
+}
+
+// whether a file at path is no deeper than config.MaxDepth allows
+func withinDepth(path string, config Config) bool {
+	return config.MaxDepth <= 0 || pathDepth(path) <= config.MaxDepth
+}
+
+// config.MaxDepth counted the way fd and rg count it, from the folder
+// they're searching rather than the top of the vault. With several folders
+// at different depths it's the most the shallowest one allows, so what's
+// found needs checking withinDepth after.
+func searchDepth(config Config) int {
+	depth := 0
+	for _, folder := range config.Folders {
+		if allowed := config.MaxDepth - pathDepth(folder); allowed > depth {
+			depth = allowed
+		}
+	}
+	if len(config.Folders) == 0 {
+		depth = config.MaxDepth
+	}
+	return depth
+}
+
+// the folders of config.Folders with anything shallow enough in them
+func foldersWithinDepth(config Config) []string {
+	if config.MaxDepth <= 0 {
+		return config.Folders
+	}
+	var folders []string
+	for _, folder := range config.Folders {
+		if pathDepth(folder) < config.MaxDepth {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
@@ -0,0 +1,107 @@
+package osearch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BenchResult is how one backend fared running the same search over and
+// over
+type BenchResult struct {
+	Backend string
+	// how many results the search found, or -1 if it failed
+	Results int
+	Min     time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	Err     error
+}
+
+// the backends this machine can run: fd and rg only if they're installed
+func availableBackends() []string {
+	backends := []string{BackendNative, BackendIndex}
+	for _, tool := range []string{"/usr/local/bin/fd", "/usr/local/bin/rg"} {
+		if _, err := os.Stat(tool); err != nil {
+			return backends
+		}
+	}
+	return append([]string{BackendExternal}, backends...)
+}
+
+// Bench runs a search the given number of times on each backend, or every
+// available one if there are none, after one run that isn't timed so the
+// index and the OS's caches are warm
+func Bench(options Options, backends []string, runs int) []BenchResult {
+	if len(backends) == 0 {
+		backends = availableBackends()
+	}
+	if runs < 1 {
+		runs = 1
+	}
+	// a benchmark is no search to remember
+	options.Config.History = 0
+	options.Config.Backends = nil
+
+	var results []BenchResult
+	for _, backend := range backends {
+		options.Config.Backend = backend
+		result := BenchResult{Backend: backend, Results: -1}
+		found, err := Search(options)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		result.Results = len(found.Items)
+
+		times := make([]time.Duration, runs)
+		for run := range times {
+			start := time.Now()
+			Search(options)
+			times[run] = time.Since(start)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		result.Min = times[0]
+		result.P50 = percentile(times, 50)
+		result.P90 = percentile(times, 90)
+		result.P99 = percentile(times, 99)
+		result.Max = times[len(times)-1]
+		results = append(results, result)
+	}
+	return results
+}
+
+// the nearest-rank percentile of sorted times
+func percentile(times []time.Duration, percent int) time.Duration {
+	rank := (percent*len(times) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return times[rank-1]
+}
+
+// WriteBench prints the results as a table
+func WriteBench(out io.Writer, results []BenchResult) error {
+	var report strings.Builder
+	fmt.Fprintf(&report, "%-8s %8s %9s %9s %9s %9s %9s\n", "backend", "results", "min", "p50", "p90", "p99", "max")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(&report, "%-8s failed: %s\n", result.Backend, result.Err)
+			continue
+		}
+		fmt.Fprintf(&report, "%-8s %8d %9s %9s %9s %9s %9s\n", result.Backend, result.Results,
+			benchTime(result.Min), benchTime(result.P50), benchTime(result.P90), benchTime(result.P99), benchTime(result.Max))
+	}
+	_, err := io.WriteString(out, report.String())
+	return err
+}
+
+func benchTime(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+// +build darwin
+
+package osearch
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// when the file was created, which macOS keeps track of
+func birthTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec)
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,15 @@
+//go:build !darwin
+// +build !darwin
+
+package osearch
+
+import (
+	"os"
+	"time"
+)
+
+// elsewhere creation times aren't reliably available, so the modification
+// time has to do
+func birthTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,171 @@
+package osearch
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// bumped whenever what's saved, or how trigrams are made, changes
+const bloomVersion = 2
+
+// with ten bits and seven hashes per trigram, about one file in a hundred
+// that lacks a trigram looks as if it has it
+const (
+	bloomBitsPerTrigram = 10
+	bloomHashes         = 7
+)
+
+// bloomFilter answers whether a file may have a trigram: a no is certain,
+// a yes only likely, in a small fraction of the space a list would take
+type bloomFilter []uint64
+
+func newBloomFilter(trigrams []uint64) bloomFilter {
+	words := (len(trigrams)*bloomBitsPerTrigram + 63) / 64
+	if words == 0 {
+		words = 1
+	}
+	filter := make(bloomFilter, words)
+	for _, trigram := range trigrams {
+		filter.each(trigram, func(bit uint64) {
+			filter[bit/64] |= 1 << (bit % 64)
+		})
+	}
+	return filter
+}
+
+// the bits for a trigram, by double hashing two halves of a mixed hash
+func (filter bloomFilter) each(trigram uint64, do func(bit uint64)) {
+	hash := splitmix64(trigram)
+	first, second := hash&0xffffffff, hash>>32|1
+	size := uint64(len(filter)) * 64
+	for index := uint64(0); index < bloomHashes; index++ {
+		do((first + index*second) % size)
+	}
+}
+
+func (filter bloomFilter) mayHaveAll(trigrams []uint64) bool {
+	for _, trigram := range trigrams {
+		has := true
+		filter.each(trigram, func(bit uint64) {
+			if filter[bit/64]&(1<<(bit%64)) == 0 {
+				has = false
+			}
+		})
+		if !has {
+			return false
+		}
+	}
+	return true
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// the Bloom filters of a vault's files, kept in the data folder so the
+// native backend can tell which files aren't worth reading
+type bloomCache struct {
+	Version int
+	Files   map[string]bloomEntry
+	changed bool
+	// the vault's folder, which the files are relative to
+	directory string
+}
+
+type bloomEntry struct {
+	ModTime int64
+	Size    int64
+	Binary  bool
+	Filter  bloomFilter
+}
+
+func bloomFile(directory string) string {
+	return vaultDataFile(directory, "bloom", "gob")
+}
+
+func loadBloomCache(directory string) *bloomCache {
+	cache := &bloomCache{Version: bloomVersion, Files: make(map[string]bloomEntry), directory: directory}
+	file, err := os.Open(bloomFile(directory))
+	if err != nil {
+		return cache
+	}
+	defer file.Close()
+	err = gob.NewDecoder(file).Decode(cache)
+	if err != nil || cache.Version != bloomVersion {
+		return &bloomCache{Version: bloomVersion, Files: make(map[string]bloomEntry), directory: directory}
+	}
+	return cache
+}
+
+// a file's text if it may match a pattern with trigrams in it. A file
+// whose filter is up to date and lacks one of them isn't read at all, and
+// nor is one known to be binary.
+func (cache *bloomCache) read(file string, trigrams []uint64) ([]byte, bool) {
+	path := filepath.Join(cache.directory, file)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := cache.Files[file]
+	if ok && entry.ModTime == info.ModTime().UnixNano() && entry.Size == info.Size() {
+		if entry.Binary || !entry.Filter.mayHaveAll(trigrams) {
+			return nil, false
+		}
+		return readText(path)
+	}
+
+	content, isText := readText(path)
+	entry = bloomEntry{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Binary: !isText}
+	if isText {
+		entry.Filter = newBloomFilter(textTrigrams(string(content)))
+	}
+	cache.Files[file] = entry
+	cache.changed = true
+	return content, isText
+}
+
+// forget files that weren't seen in a walk of the whole vault
+func (cache *bloomCache) keepOnly(seen map[string]bool) {
+	for file := range cache.Files {
+		if !seen[file] {
+			delete(cache.Files, file)
+			cache.changed = true
+		}
+	}
+}
+
+func (cache *bloomCache) save() {
+	if !cache.changed {
+		return
+	}
+	filename := bloomFile(cache.directory)
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err == nil {
+		// a file of its own, since another search may be saving too
+		var file *os.File
+		file, err = ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+		if err == nil {
+			err = gob.NewEncoder(file).Encode(cache)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err == nil {
+				err = os.Rename(file.Name(), filename)
+			}
+			if err != nil {
+				os.Remove(file.Name())
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("could not save the Bloom filters: %s", err)
+		return
+	}
+	cache.changed = false
+}
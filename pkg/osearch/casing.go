@@ -0,0 +1,46 @@
+package osearch
+
+import (
+	"unicode"
+)
+
+// how searches treat upper and lower case
+const (
+	// ignore case unless the query has a capital letter in it, like rg and fd
+	SmartCase       = "smart"
+	CaseSensitive   = "sensitive"
+	CaseInsensitive = "ignore"
+)
+
+// whether mode is one of the above, or empty for smart case
+func isCaseMode(mode string) bool {
+	switch mode {
+	case SmartCase, CaseSensitive, CaseInsensitive, "":
+		return true
+	}
+	return false
+}
+
+func isCaseSensitive(query string, mode string) bool {
+	switch mode {
+	case CaseSensitive:
+		return true
+	case CaseInsensitive:
+		return false
+	}
+	for _, r := range query {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// pattern made to match case or not as query and mode call for, so it
+// carries that with it into a regex with other patterns
+func withCase(pattern string, query string, mode string) string {
+	if isCaseSensitive(query, mode) {
+		return "(?:" + pattern + ")"
+	}
+	return "(?i:" + pattern + ")"
+}
@@ -0,0 +1,185 @@
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CompletionFlag is a flag shell completion offers
+type CompletionFlag struct {
+	Name  string
+	Usage string
+	// whether the flag is followed by a value, unlike --grep
+	TakesValue bool
+}
+
+// the values flags take, where there's a fixed list of them
+var completionValues = map[string][]string{
+	"backend":        {BackendExternal, BackendNative, BackendIndex},
+	"format":         {FormatAlfred, FormatRaycast, FormatLaunchBar, FormatLua, FormatJson, FormatJsonLines, FormatPlain, FormatTsv},
+	"sort":           {SortRelevance, SortModified, SortCreated, SortTitle, SortPath},
+	"group-by":       {"folder", "vault"},
+	"dedupe":         {DedupeFirst, DedupeBest, DedupeOff},
+	"action":         {ActionObsidian, ActionEditor, ActionContents, ActionWikilink, ActionMarkdownLink},
+	"skip-knowledge": {"auto", "true", "false"},
+}
+
+// flags whose value is a file or folder
+var completionFiles = []string{"path", "from", "config"}
+
+// VaultNames lists the vaults Obsidian knows about by their folder's name,
+// which is what --vault and Obsidian's URLs take
+func VaultNames(obsidianConfig string) []string {
+	var known ObsidianConfig
+	content, err := ioutil.ReadFile(obsidianConfig)
+	if err != nil {
+		return nil
+	}
+	json.Unmarshal(content, &known)
+	var names []string
+	for _, vault := range known.Vaults {
+		names = append(names, filepath.Base(vault.Path))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SavedSearchNames lists the config's saved searches
+func SavedSearchNames(config Config) []string {
+	var names []string
+	for name := range config.Searches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteCompletion writes a script completing osearch's subcommands and
+// flags for shell (bash, zsh or fish). Vault and saved search names are
+// looked up when completing, by running osearch completion vaults and
+// osearch completion searches.
+func WriteCompletion(out io.Writer, shell string, subcommands []string, flags []CompletionFlag) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(out, subcommands, flags)
+	case "zsh":
+		writeZshCompletion(out, subcommands, flags)
+	case "fish":
+		writeFishCompletion(out, subcommands, flags)
+	default:
+		return fmt.Errorf("can't complete for %s, only bash, zsh or fish", shell)
+	}
+	return nil
+}
+
+func flagNames(flags []CompletionFlag) string {
+	var names []string
+	for _, flag := range flags {
+		names = append(names, "--"+flag.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func sortedValueFlags() []string {
+	var names []string
+	for name := range completionValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeBashCompletion(out io.Writer, subcommands []string, flags []CompletionFlag) {
+	fmt.Fprintln(out, "# osearch completion for bash: source this from ~/.bashrc")
+	fmt.Fprintln(out, "_osearch() {")
+	fmt.Fprintln(out, `	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" IFS=$'\n'`)
+	fmt.Fprintln(out, `	case "$prev" in`)
+	fmt.Fprintln(out, `	--vault) COMPREPLY=($(compgen -W "$(osearch completion vaults 2>/dev/null)" -- "$cur")); return ;;`)
+	fmt.Fprintf(out, "\t--%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(completionFiles, "|--"))
+	for _, name := range sortedValueFlags() {
+		fmt.Fprintf(out, "\t--%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", name, strings.Join(completionValues[name], "\n"))
+	}
+	fmt.Fprintln(out, "\tesac")
+	fmt.Fprintln(out, `	if [[ $cur == -* ]]; then`)
+	fmt.Fprintf(out, "\t\tCOMPREPLY=($(IFS=' ' compgen -W \"%s\" -- \"$cur\"))\n", flagNames(flags))
+	fmt.Fprintln(out, `	elif [[ $COMP_CWORD -eq 1 ]]; then`)
+	fmt.Fprintf(out, "\t\tCOMPREPLY=($(IFS=' ' compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(subcommands, " "))
+	fmt.Fprintln(out, `	elif [[ $'\n'"${COMP_WORDS[*]}"$'\n' == *$'\n--saved\n'* ]]; then`)
+	fmt.Fprintln(out, `		COMPREPLY=($(compgen -W "$(osearch completion searches 2>/dev/null)" -- "$cur"))`)
+	fmt.Fprintln(out, "\tfi")
+	fmt.Fprintln(out, "}")
+	fmt.Fprintln(out, "complete -o default -F _osearch osearch")
+}
+
+func writeZshCompletion(out io.Writer, subcommands []string, flags []CompletionFlag) {
+	fmt.Fprintln(out, "#compdef osearch")
+	fmt.Fprintln(out, "# osearch completion for zsh: save as _osearch somewhere in $fpath")
+	fmt.Fprintln(out, "_osearch() {")
+	fmt.Fprintln(out, "\tlocal -a values")
+	fmt.Fprintln(out, `	case "${words[CURRENT-1]}" in`)
+	fmt.Fprintln(out, `	--vault) values=("${(@f)$(osearch completion vaults 2>/dev/null)}"); compadd -a values; return ;;`)
+	fmt.Fprintf(out, "\t--%s) _files; return ;;\n", strings.Join(completionFiles, "|--"))
+	for _, name := range sortedValueFlags() {
+		fmt.Fprintf(out, "\t--%s) compadd %s; return ;;\n", name, strings.Join(completionValues[name], " "))
+	}
+	fmt.Fprintln(out, "\tesac")
+	fmt.Fprintln(out, `	if [[ $PREFIX == -* ]]; then`)
+	fmt.Fprintln(out, "\t\tlocal -a flags")
+	fmt.Fprintln(out, "\t\tflags=(")
+	for _, flag := range flags {
+		fmt.Fprintf(out, "\t\t\t%s\n", zshQuote("--"+flag.Name+":"+strings.ReplaceAll(flag.Usage, ":", `\:`)))
+	}
+	fmt.Fprintln(out, "\t\t)")
+	fmt.Fprintln(out, "\t\t_describe flag flags")
+	fmt.Fprintln(out, "\telif (( CURRENT == 2 )); then")
+	fmt.Fprintf(out, "\t\tcompadd %s\n", strings.Join(subcommands, " "))
+	fmt.Fprintln(out, "\telif (( ${words[(I)--saved]} )); then")
+	fmt.Fprintln(out, `		values=("${(@f)$(osearch completion searches 2>/dev/null)}")`)
+	fmt.Fprintln(out, "\t\tcompadd -a values")
+	fmt.Fprintln(out, "\telse")
+	fmt.Fprintln(out, "\t\t_files")
+	fmt.Fprintln(out, "\tfi")
+	fmt.Fprintln(out, "}")
+	fmt.Fprintln(out, `_osearch "$@"`)
+}
+
+func writeFishCompletion(out io.Writer, subcommands []string, flags []CompletionFlag) {
+	fmt.Fprintln(out, "# osearch completion for fish: save as ~/.config/fish/completions/osearch.fish")
+	fmt.Fprintf(out, "complete -c osearch -n __fish_use_subcommand -f -a %s\n", fishQuote(strings.Join(subcommands, " ")))
+	fmt.Fprintln(out, "complete -c osearch -n '__fish_contains_opt saved' -f -a '(osearch completion searches 2>/dev/null)'")
+	for _, flag := range flags {
+		line := fmt.Sprintf("complete -c osearch -l %s -d %s", flag.Name, fishQuote(flag.Usage))
+		if values, ok := completionValues[flag.Name]; ok {
+			line += " -x -a " + fishQuote(strings.Join(values, " "))
+		} else if flag.Name == "vault" {
+			line += " -x -a '(osearch completion vaults 2>/dev/null)'"
+		} else if flag.TakesValue && isCompletionFile(flag.Name) {
+			line += " -r -F"
+		} else if flag.TakesValue {
+			line += " -x"
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+func isCompletionFile(name string) bool {
+	for _, file := range completionFiles {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+func zshQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+func fishQuote(text string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(text) + "'"
+}
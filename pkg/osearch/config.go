@@ -0,0 +1,199 @@
+package osearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// settings read from config.json in the data directory. Command line flags
+// override whatever the file says.
+type Config struct {
+	// icon images for results under a folder, keyed by vault-relative path
+	FolderIcons map[string]string `json:"folderIcons"`
+	// how content search results are ranked
+	Ranking RankingWeights `json:"ranking"`
+	// vault-relative paths of notes that always come first when they match
+	Pinned []string `json:"pinned"`
+	// vault-relative paths of notes and folders never to show
+	Ignore []string `json:"ignore"`
+	// how many typos a word in a content search may have
+	Typos int `json:"typos"`
+	// how many matching lines of a note content search shows
+	PerFile int `json:"-"`
+	// which of them: the first, the best or every one
+	Dedupe string `json:"dedupe"`
+	// the vault folders to search in, or none for all of it
+	Folders []string `json:"-"`
+	// globs of notes and folders to leave out of this search, from --exclude
+	Exclude []string `json:"-"`
+	// set by --regex: the search is a regular expression, not words
+	Regex bool `json:"-"`
+	// whether content search matches other forms of English words
+	Stem bool `json:"stem"`
+	// how many words either side of a content match to show, or 0 to show
+	// as much of the line as fits
+	Context int `json:"context"`
+	// whether a file name search that finds nothing searches contents instead
+	Fallback bool `json:"fallback"`
+	// set by --frontmatter: content search looks only at frontmatter
+	Frontmatter bool `json:"-"`
+	// whether content search skips matches in fenced code blocks
+	NoCode bool `json:"noCode"`
+	// whether content search skips matches in frontmatter
+	NoFrontmatter bool `json:"noFrontmatter"`
+	// what to put either side of the match in a content search subtitle,
+	// one marker or an opening and closing one separated by a space
+	Markers string `json:"markers"`
+	// set by --count: content search shows how often each note matches
+	// instead of the matching lines
+	Count bool `json:"-"`
+	// set by --multiline: content matches may span lines
+	Multiline bool `json:"-"`
+	// whether subtitles say how long each note is
+	WordCount bool `json:"wordCount"`
+	// whether subtitles start with the name of the vault a note is in
+	ShowVault bool `json:"showVault"`
+	// whether results are titled by file name even when a note gives
+	// itself a title in its frontmatter or first heading
+	FileTitles bool `json:"fileTitles"`
+	// smart, sensitive or ignore
+	Case string `json:"case"`
+	// fd, native or index
+	Backend string `json:"backend"`
+	// the backend for particular modes, overriding Backend
+	Backends map[string]string `json:"backends"`
+
+	// how many folders deep into the vault to look, where 1 is only the
+	// notes at the top; 0 looks everywhere
+	MaxDepth int `json:"maxDepth"`
+	// whether to search folders and notes that are symlinks
+	Follow bool `json:"follow"`
+	// whether to search hidden folders and notes, whose names start with a
+	// dot
+	Hidden bool `json:"hidden"`
+	// hidden folders that stay out of the search even so
+	HiddenExclude []string `json:"hiddenExclude"`
+	// extra arguments for fd when searching file names, ahead of the ones
+	// osearch passes
+	FdArgs []string `json:"fdArgs"`
+	// extra arguments for rg, ahead of the ones osearch passes
+	RgArgs []string `json:"rgArgs"`
+
+	// whether notes in the templates folder show up outside --templates
+	IncludeTemplates bool `json:"includeTemplates"`
+	// whether the copies sync tools make of conflicting edits show up
+	// outside --conflicts
+	IncludeConflicts bool `json:"includeConflicts"`
+	// whether to leave out whatever is in the attachment folder set in
+	// Obsidian, when there's one for the whole vault
+	ExcludeAttachments bool `json:"excludeAttachments"`
+	// whether to have iCloud Drive download the notes it's evicted from the
+	// vault when a search shows them, so their contents can be searched too
+	ICloudDownload bool `json:"icloudDownload"`
+	// what opens notes outside Obsidian: an app, like "Visual Studio Code",
+	// or a command, like "code"; empty means $VISUAL or $EDITOR
+	Editor string `json:"editor"`
+	// what picking a result does: open the note in Obsidian, or in the
+	// editor
+	Action string `json:"action"`
+	// whether to search vaults nested inside the one being searched
+	NestedVaults bool `json:"nestedVaults"`
+	// words of a query to search for as something else, such as "k8s":
+	// "kubernetes"
+	Synonyms map[string]string `json:"synonyms"`
+	// searches to run by name with --saved, such as "inbox": "tag:inbox
+	// sort:modified"
+	Searches map[string]string `json:"searches"`
+	// how many searches to remember, shown when the query is empty; 0
+	// keeps no history
+	History int `json:"history"`
+
+	// the command --semantic runs to embed text: it gets the text on its
+	// standard input and prints a JSON list of numbers
+	EmbedCommand []string `json:"embedCommand"`
+
+	// what the search runs on, picked from Backend and Backends
+	backend SearchBackend
+	// the vault's folder, which the paths a search finds are relative to
+	directory string
+	// whether listings take in iCloud's placeholders for evicted notes too,
+	// whatever their pattern
+	placeholders bool
+	// what's given each result as soon as it's found, when streaming
+	found func(AlfredResult)
+}
+
+// hand result over as soon as it's found, if the search is streaming
+func (config Config) emit(result AlfredResult) {
+	if config.found != nil {
+		config.found(result)
+	}
+}
+
+// where osearch keeps its own files: Alfred's workflow data folder when run
+// from a workflow, otherwise somewhere sensible in the home directory
+func dataDir() string {
+	if dir := os.Getenv("alfred_workflow_data"); len(dir) > 0 {
+		return dir
+	}
+	return ExpandHome("~/Library/Application Support/osearch")
+}
+
+// Obsidian's settings, its trash and git's history are hidden for good
+// reason
+var DefaultHiddenExclude = []string{".obsidian", ".trash", ".git"}
+
+// DefaultConfigFile is where the config lives unless --config says otherwise
+func DefaultConfigFile() string {
+	return filepath.Join(dataDir(), "config.json")
+}
+
+// LoadConfig reads the config file over the defaults; a missing file just
+// means the defaults
+func LoadConfig(configFile string) (Config, error) {
+	config := Config{Ranking: DefaultRankingWeights, Fallback: true, HiddenExclude: DefaultHiddenExclude}
+	content, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, exitError(ExitConfig, "could not open %s", configFile)
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return config, exitError(ExitConfig, "could not parse %s: %s", configFile, err)
+	}
+	if !isCaseMode(config.Case) {
+		return config, exitError(ExitConfig, "unknown case mode %s in %s", config.Case, configFile)
+	}
+	return config, nil
+}
+
+// set one top-level key in the config file, leaving the rest as it was
+func updateConfig(configFile string, key string, value interface{}) error {
+	settings := make(map[string]json.RawMessage)
+	content, err := ioutil.ReadFile(configFile)
+	if err == nil {
+		err = json.Unmarshal(content, &settings)
+		if err != nil {
+			return exitError(ExitConfig, "could not parse %s: %s", configFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return exitError(ExitConfig, "could not open %s", configFile)
+	}
+
+	encoded, _ := json.Marshal(value)
+	settings[key] = encoded
+	content, _ = json.MarshalIndent(settings, "", "  ")
+
+	err = os.MkdirAll(filepath.Dir(configFile), 0700)
+	if err == nil {
+		err = ioutil.WriteFile(configFile, append(content, '\n'), 0600)
+	}
+	if err != nil {
+		return exitError(ExitConfig, "could not save %s: %s", configFile, err)
+	}
+	return nil
+}
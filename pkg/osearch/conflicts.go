@@ -0,0 +1,64 @@
+package osearch
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// the marks sync tools leave in the names of the copies they make when a
+// note changed in two places at once: Dropbox's and Nextcloud's "Note
+// (conflicted copy 2024-03-01).md", with whose copy it was in Dropbox's
+// case, and Syncthing's "Note.sync-conflict-20240301-120000-ABCDEFG.md"
+var conflictPattern = regexp.MustCompile(`(?i) \((?:[^()]*['’]s )?conflicted copy[^()]*\)|\.sync-conflict-\d{8}-\d{6}(?:-[A-Z0-9]+)?`)
+
+// whether filename is a sync tool's conflicted copy of a note
+func isConflict(filename string) bool {
+	return conflictPattern.MatchString(filepath.Base(filename))
+}
+
+// the name of the note a conflicted copy was made of
+func conflictOriginal(filename string) string {
+	return conflictPattern.ReplaceAllString(filepath.Base(filename), "")
+}
+
+// leave the conflicted copies out of results
+func withoutConflicts(results []AlfredResult) []AlfredResult {
+	var kept []AlfredResult
+	for _, result := range results {
+		if !isConflict(result.Variables["path"]) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// the conflicted copies whose names match, newest first, each saying which
+// note it's a copy of so they can be compared and cleaned up
+func conflictResults(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	if found := config.found; found != nil {
+		config.found = func(result AlfredResult) {
+			if isConflict(result.Variables["path"]) {
+				found(asConflict(result))
+			}
+		}
+	}
+	results, err := findMatchingFiles(searchTerm, directory, vault, config)
+	var conflicts []AlfredResult
+	for _, result := range results.Items {
+		if isConflict(result.Variables["path"]) {
+			conflicts = append(conflicts, asConflict(result))
+		}
+	}
+	sortByModified(conflicts)
+	return AlfredResults{Items: conflicts}, err
+}
+
+// result, saying which note it's a conflicted copy of
+func asConflict(result AlfredResult) AlfredResult {
+	subtitle := "copy of " + toNFC(withoutMd(conflictOriginal(result.Variables["path"])))
+	if len(result.Subtitle) > 0 {
+		subtitle += " · " + result.Subtitle
+	}
+	result.Subtitle = subtitle
+	return result
+}
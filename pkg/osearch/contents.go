@@ -0,0 +1,63 @@
+package osearch
+
+import (
+	"strings"
+)
+
+// NoteContents is the text of the note at file to paste elsewhere: all of
+// it after the frontmatter, or with line, the section around that line of
+// the file, from the heading above it to the next heading as big
+func NoteContents(file string, line int) (string, error) {
+	content, err := readNote(file)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimPrefix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\ufeff")
+	_, body := parseFrontmatter(text)
+	if line <= 0 {
+		return strings.TrimSpace(body), nil
+	}
+	lines := strings.Split(text, "\n")
+	bodyStart := len(lines) - len(strings.Split(body, "\n"))
+	if line > len(lines) || line <= bodyStart {
+		return strings.TrimSpace(body), nil
+	}
+
+	levels := headingLevels(lines, bodyStart)
+	start := bodyStart
+	level := 0
+	for index := line - 1; index >= bodyStart; index-- {
+		if levels[index] > 0 {
+			start = index
+			level = levels[index]
+			break
+		}
+	}
+	end := len(lines)
+	for index := line; index < len(lines); index++ {
+		if levels[index] > 0 && (level == 0 || levels[index] <= level) {
+			end = index
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n")), nil
+}
+
+// the level of each line that's a heading, from # to ######, counting only
+// those from start on and outside code blocks; 0 for the rest
+func headingLevels(lines []string, start int) []int {
+	levels := make([]int, len(lines))
+	fenced := false
+	for index := start; index < len(lines); index++ {
+		line := lines[index]
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || !isHeading(line) || !strings.HasPrefix(line, "#") {
+			continue
+		}
+		levels[index] = len(line) - len(strings.TrimLeft(line, "#"))
+	}
+	return levels
+}
@@ -0,0 +1,49 @@
+package osearch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// one result per note saying how often the terms turn up in it, most
+// first: the note a topic lives in tends to mention it the most
+func countResults(matches []*fileMatches, terms []string, directory string, vault string, config Config) []AlfredResult {
+	alternatives := make([]string, len(terms))
+	for index, term := range terms {
+		alternatives[index] = termPattern(term, config)
+	}
+	pattern, err := regexp.Compile(strings.Join(alternatives, "|"))
+
+	counts := make(map[string]int)
+	for _, m := range matches {
+		for _, line := range m.lines {
+			found := 1
+			if err == nil {
+				if spans := pattern.FindAllStringIndex(line.text, -1); len(spans) > 0 {
+					found = len(spans)
+				}
+			}
+			counts[m.filename] += found
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return counts[matches[i].filename] > counts[matches[j].filename]
+	})
+
+	var results []AlfredResult
+	for _, m := range matches {
+		result := noteResult(m.filename, directory, vault, config)
+		result.Title = fmt.Sprintf("%s — %s", result.Title, countMatches(counts[m.filename]))
+		results = append(results, result)
+	}
+	return results
+}
+
+func countMatches(count int) string {
+	if count == 1 {
+		return "1 match"
+	}
+	return groupThousands(count) + " matches"
+}
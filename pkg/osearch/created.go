@@ -0,0 +1,76 @@
+package osearch
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var frontmatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseFrontmatterDate(value string) (time.Time, bool) {
+	for _, layout := range frontmatterDateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// createdTime is when a note was written according to the created or date
+// field in its frontmatter, which survives sync tools and bulk edits that
+// reset file times. Without one it's when the file was created.
+func createdTime(fullPath string) (time.Time, bool) {
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if strings.HasSuffix(fullPath, ".md") {
+		if content, err := readNote(fullPath); err == nil {
+			frontmatter, _ := parseFrontmatter(string(content))
+			for _, key := range []string{"created", "date"} {
+				if values := frontmatter[key]; len(values) == 1 {
+					if t, ok := parseFrontmatterDate(values[0]); ok {
+						return t, true
+					}
+				}
+			}
+		}
+	}
+	return birthTime(info), true
+}
+
+// the results created between since and before; a zero time leaves that
+// end open
+func createdBetween(results []AlfredResult, since time.Time, before time.Time) []AlfredResult {
+	if since.IsZero() && before.IsZero() {
+		return results
+	}
+	var kept []AlfredResult
+	for _, result := range results {
+		created, ok := createdTime(result.Variables["fullpath"])
+		if ok && (since.IsZero() || !created.Before(since)) && (before.IsZero() || created.Before(before)) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// newest first
+func sortByCreated(results []AlfredResult) {
+	created := make(map[string]time.Time)
+	for _, result := range results {
+		created[result.UID], _ = createdTime(result.Variables["fullpath"])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return created[results[i].UID].After(created[results[j].UID])
+	})
+}
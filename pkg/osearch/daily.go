@@ -0,0 +1,49 @@
+package osearch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ParseDay reads which day's daily note is wanted: today, yesterday,
+// tomorrow, a date, or how long ago (3d)
+func ParseDay(value string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+	return ParseTimeBound(value, now)
+}
+
+// obsidianNewUrl makes a note at path in Obsidian and opens it
+func obsidianNewUrl(path string, vault string) string {
+	return fmt.Sprintf("obsidian://new?vault=%s&file=%s", vault, uriComponent(withoutMd(path)))
+}
+
+// DailyNote is the daily note for day, where the daily notes plugin puts
+// it and named as it names them, or an item to make it if there isn't one
+// yet
+func DailyNote(directory string, vault string, config Config, day time.Time) AlfredResults {
+	path := dailyNotes(directory).path(day)
+	if _, err := os.Stat(filepath.Join(directory, path)); err == nil {
+		return AlfredResults{Items: []AlfredResult{noteResult(path, directory, vault, config)}}
+	}
+	return AlfredResults{Items: []AlfredResult{{
+		Type:     "default",
+		Title:    withoutMd(filepath.Base(path)),
+		Subtitle: "Create " + filepath.ToSlash(path),
+		Arg:      obsidianNewUrl(path, vault),
+		Variables: map[string]string{
+			"vault":    vault,
+			"path":     path,
+			"fullpath": filepath.Join(directory, path),
+		},
+	}}}
+}
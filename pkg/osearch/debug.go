@@ -0,0 +1,91 @@
+package osearch
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the debug log rolls over to debug.log.1, .2 and .3 at this size
+const (
+	debugLogSize  = 1 << 20
+	debugLogsKept = 3
+)
+
+// where --debug writes, or nil when it's off
+var debugLog *log.Logger
+
+func debugFile() string {
+	return filepath.Join(dataDir(), "debug.log")
+}
+
+// EnableDebug starts writing what osearch does to debug.log in the data
+// folder, for troubleshooting inside Alfred where nothing sees stderr
+func EnableDebug() error {
+	err := os.MkdirAll(dataDir(), 0700)
+	if err != nil {
+		return err
+	}
+	filename := debugFile()
+	if info, err := os.Stat(filename); err == nil && info.Size() > debugLogSize {
+		for kept := debugLogsKept - 1; kept >= 1; kept-- {
+			os.Rename(fmt.Sprintf("%s.%d", filename, kept), fmt.Sprintf("%s.%d", filename, kept+1))
+		}
+		os.Rename(filename, filename+".1")
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	debugLog = log.New(file, "", 0)
+	Debug("start", "pid", os.Getpid(), "version", Version, "commit", Commit, "built", BuildDate, "args", strings.Join(os.Args[1:], " "))
+	return nil
+}
+
+// Debug logs an event with its details as key=value pairs on one line, as
+// logfmt does, if --debug is on
+func Debug(event string, keysAndValues ...interface{}) {
+	if debugLog == nil {
+		return
+	}
+	var line strings.Builder
+	line.WriteString("time=" + time.Now().Format(time.RFC3339Nano) + " event=" + event)
+	for index := 0; index+1 < len(keysAndValues); index += 2 {
+		line.WriteString(fmt.Sprintf(" %v=%s", keysAndValues[index], debugValue(keysAndValues[index+1])))
+	}
+	debugLog.Println(line.String())
+}
+
+// a value quoted if it has to be
+func debugValue(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case time.Duration:
+		text = strconv.FormatFloat(v.Seconds()*1000, 'f', 2, 64) + "ms"
+	case error:
+		text = v.Error()
+	default:
+		text = fmt.Sprint(v)
+	}
+	if text == "" || strings.ContainsAny(text, " =\"\t\n") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// run a command for its output, logging it and how long it took
+func commandOutput(command *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	out, err := command.Output()
+	if err != nil {
+		Debug("command", "args", strings.Join(command.Args, " "), "took", time.Since(start), "bytes", len(out), "error", err)
+	} else {
+		Debug("command", "args", strings.Join(command.Args, " "), "took", time.Since(start), "bytes", len(out))
+	}
+	return out, err
+}
@@ -0,0 +1,95 @@
+package osearch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// the ways a result's main action can open its note
+const (
+	// open it in Obsidian
+	ActionObsidian = "obsidian"
+	// open it in the editor config names
+	ActionEditor = "editor"
+	// copy its text, or the section that matched
+	ActionContents = "contents"
+	// copy a wikilink to it
+	ActionWikilink = "wikilink"
+	// copy a markdown link to it
+	ActionMarkdownLink = "markdown"
+)
+
+// the modifier whose action each of the actions above takes over
+var actionMods = map[string]string{
+	ActionEditor:       "fn",
+	ActionContents:     "cmd+alt",
+	ActionWikilink:     "ctrl",
+	ActionMarkdownLink: "ctrl+shift",
+}
+
+// what opens notes outside Obsidian: config.Editor, else $VISUAL or
+// $EDITOR, else whatever macOS opens text files with
+func editorName(config Config) string {
+	for _, editor := range []string{config.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if len(strings.TrimSpace(editor)) > 0 {
+			return strings.TrimSpace(editor)
+		}
+	}
+	return ""
+}
+
+// the command opening file in editor. An editor that's a command on the
+// PATH, like "code" or "subl -n", is run with the file after its own
+// arguments; anything else is taken to be an app, like "Visual Studio
+// Code" or "/Applications/Typora.app", and opened with open -a. No editor
+// at all opens the default text editor.
+func editorCommand(editor string, file string) *exec.Cmd {
+	if len(editor) == 0 {
+		return exec.Command("open", "-t", file)
+	}
+	if !strings.HasSuffix(editor, ".app") {
+		words := strings.Fields(editor)
+		if _, err := exec.LookPath(words[0]); err == nil {
+			return exec.Command(words[0], append(words[1:], file)...)
+		}
+	}
+	return exec.Command("open", "-a", editor, file)
+}
+
+// OpenInEditor opens the note at file in the editor config names instead
+// of Obsidian, without waiting for it to be closed
+func OpenInEditor(file string, config Config) error {
+	command := editorCommand(editorName(config), ExpandHome(file))
+	Debug("edit", "file", file, "command", strings.Join(command.Args, " "))
+	return command.Start()
+}
+
+// how the edit action names the editor in its subtitle
+func editorLabel(config Config) string {
+	editor := editorName(config)
+	if len(editor) == 0 {
+		return "text editor"
+	}
+	if command := editorCommand(editor, ""); command.Args[0] != "open" {
+		return filepath.Base(command.Args[0])
+	}
+	return strings.TrimSuffix(filepath.Base(editor), ".app")
+}
+
+// make what the modifier key does the main action of results, moving
+// opening them in Obsidian to the modifier
+func withMainAction(results []AlfredResult, key string) {
+	for index := range results {
+		result := &results[index]
+		mod, ok := result.Mods[key]
+		if !ok {
+			continue
+		}
+		obsidian := modAction("open", result.Arg, "Open in Obsidian", result.Variables)
+		result.Arg = mod.Arg
+		result.Variables = mod.Variables
+		result.Mods[key] = obsidian
+	}
+}
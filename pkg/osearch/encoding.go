@@ -0,0 +1,116 @@
+package osearch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// the characters Windows-1252 has where Latin-1 has control codes; the
+// rest of both map straight onto the first 256 code points
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// readNote reads a note as UTF-8, whatever it was written in
+func readNote(file string) ([]byte, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return toUtf8(content), nil
+}
+
+// toUtf8 transcodes notes old tools left in UTF-16 or Latin-1 (or really
+// Windows-1252, which is what gets called Latin-1) to UTF-8. UTF-16 is
+// known by its byte order mark or by every other byte being NUL, as ASCII
+// text in it is; anything else that isn't valid UTF-8 is taken to be
+// Windows-1252, unless it has NULs and so is most likely binary.
+func toUtf8(content []byte) []byte {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xfe}):
+		return fromUtf16(content[2:], false)
+	case bytes.HasPrefix(content, []byte{0xfe, 0xff}):
+		return fromUtf16(content[2:], true)
+	}
+	if bigEndian, ok := looksUtf16(content); ok {
+		return fromUtf16(content, bigEndian)
+	}
+	if utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return content
+	}
+	return fromWindows1252(content)
+}
+
+// whether content is UTF-16 without a byte order mark, from how many NULs
+// fall on even and odd bytes near the start
+func looksUtf16(content []byte) (bool, bool) {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if len(head) < 4 || len(content)%2 != 0 {
+		return false, false
+	}
+	var evenNuls, oddNuls int
+	for index, b := range head {
+		if b != 0 {
+			continue
+		}
+		if index%2 == 0 {
+			evenNuls++
+		} else {
+			oddNuls++
+		}
+	}
+	pairs := len(head) / 2
+	switch {
+	case oddNuls > pairs*2/5 && evenNuls == 0:
+		return false, true
+	case evenNuls > pairs*2/5 && oddNuls == 0:
+		return true, true
+	}
+	return false, false
+}
+
+// the text of a line rg gave as base64 because it isn't UTF-8, read as
+// Windows-1252 the way toUtf8 reads such notes, with the match's byte
+// offsets moved along with it
+func transcodeLine(encoded string, start int, end int) (string, int, int) {
+	line, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || start > end || end > len(line) {
+		return "", 0, 0
+	}
+	return string(fromWindows1252(line)), len(fromWindows1252(line[:start])), len(fromWindows1252(line[:end]))
+}
+
+func fromUtf16(content []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(content)/2)
+	for index := range units {
+		if bigEndian {
+			units[index] = uint16(content[2*index])<<8 | uint16(content[2*index+1])
+		} else {
+			units[index] = uint16(content[2*index+1])<<8 | uint16(content[2*index])
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+func fromWindows1252(content []byte) []byte {
+	var text bytes.Buffer
+	text.Grow(len(content) + len(content)/8)
+	for _, b := range content {
+		switch {
+		case b < 0x80:
+			text.WriteByte(b)
+		case b < 0xa0:
+			text.WriteRune(windows1252[b-0x80])
+		default:
+			text.WriteRune(rune(b))
+		}
+	}
+	return text.Bytes()
+}
@@ -0,0 +1,67 @@
+package osearch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ExitCode is the status osearch exits with, telling scripts what went
+// wrong without them reading the message
+type ExitCode int
+
+const (
+	// anything not covered below
+	ExitFailed ExitCode = 1
+	// bad flags or a missing search
+	ExitUsage ExitCode = 2
+	// osearch's config file or Obsidian's can't be read
+	ExitConfig ExitCode = 3
+	// no vault was given or found, or its folder doesn't exist
+	ExitVault ExitCode = 4
+	// the backend asked for doesn't exist, its programs aren't installed or
+	// they couldn't search
+	ExitBackend ExitCode = 5
+	// the search worked but found nothing, with --errors-json
+	ExitNoResults ExitCode = 6
+)
+
+// ExitError is a failure that says what kind it is, so the command can
+// exit with the code for it
+type ExitError struct {
+	Code ExitCode
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// an ExitError with code and a message formatted as fmt.Errorf does
+func exitError(code ExitCode, format string, v ...interface{}) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, v...)}
+}
+
+// ExitCodeFor is the code to exit with because of err: the code of the
+// ExitError it is or wraps, or ExitFailed
+func ExitCodeFor(err error) ExitCode {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	return ExitFailed
+}
+
+// the error for a program a backend runs that couldn't be, which is most
+// likely not installed
+func commandError(program string, err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return exitError(ExitBackend, "%s isn't installed: %s", program, err)
+	}
+	return fmt.Errorf("%s: %w", program, err)
+}
@@ -0,0 +1,177 @@
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// externalBackend runs fd and rg
+type externalBackend struct{}
+
+type RipGrepResult struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+			// base64 instead of text when the line isn't UTF-8
+			Bytes string `json:"bytes"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+		Submatches []struct {
+			Start int `json:"start"`
+			End   int `json:"end"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+func (externalBackend) FindFiles(pattern string, config Config) ([]string, error) {
+	args := append(append([]string{}, config.FdArgs...), "-0", "--type=f")
+	for _, folder := range foldersWithinDepth(config) {
+		args = append(args, "--search-path", folder)
+	}
+	if config.MaxDepth > 0 {
+		if searchDepth(config) < 1 {
+			return nil, nil
+		}
+		args = append(args, "--max-depth", strconv.Itoa(searchDepth(config)))
+	}
+	if config.Follow {
+		args = append(args, "--follow")
+	}
+	if config.Hidden {
+		args = append(args, "--hidden")
+		for _, excluded := range config.HiddenExclude {
+			args = append(args, "--exclude", excluded)
+		}
+	} else if config.placeholders {
+		// the placeholders are hidden, but the folders they're in mustn't be
+		args = append(args, "--hidden", "--exclude", ".*/")
+	}
+	for _, excluded := range config.Exclude {
+		args = append(args, "--exclude", excluded)
+	}
+	if len(pattern) > 0 {
+		if config.placeholders {
+			pattern = "(?:" + pattern + `)|^\..+\.icloud$`
+		}
+		args = append(args, "--case-sensitive", pattern)
+	}
+
+	// TODO: don't hardcode the path to fd
+	command := exec.Command("/usr/local/bin/fd", args...)
+	command.Dir = config.directory
+	out, err := commandOutput(command)
+	if err != nil {
+		return nil, commandError("fd", err)
+	}
+
+	var results []string
+	for _, filename := range strings.Split(string(out), "\000") {
+		if config.placeholders && !config.Hidden && strings.HasPrefix(filepath.Base(filename), ".") && !isPlaceholder(filepath.Base(filename)) {
+			continue
+		}
+		if len(filename) > 0 && withinDepth(filename, config) {
+			results = append(results, filename)
+		}
+	}
+	return results, nil
+}
+
+func (externalBackend) FilesContaining(pattern string, config Config) (map[string]bool, error) {
+	return ripgrepFiles(config, "--files-with-matches", "--case-sensitive", "--regexp", pattern)
+}
+
+func (externalBackend) GrepContent(pattern string, config Config) ([]LineMatch, error) {
+	out, err := ripgrep(config, "--json", "--case-sensitive", "--regexp", pattern)
+	if err != nil {
+		return nil, err
+	}
+	var matches []LineMatch
+	var rgr RipGrepResult
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		err := json.Unmarshal([]byte(line), &rgr)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s", line)
+		}
+		if rgr.Type != "match" || !withinDepth(rgr.Data.Path.Text, config) {
+			continue
+		}
+		match := LineMatch{Path: rgr.Data.Path.Text, Number: rgr.Data.LineNumber, Text: rgr.Data.Lines.Text}
+		if len(rgr.Data.Submatches) > 0 {
+			match.Start = rgr.Data.Submatches[0].Start
+			match.End = rgr.Data.Submatches[0].End
+		}
+		if len(match.Text) == 0 && len(rgr.Data.Lines.Bytes) > 0 {
+			match.Text, match.Start, match.End = transcodeLine(rgr.Data.Lines.Bytes, match.Start, match.End)
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// TODO: don't hardcode the path to rg
+func ripgrep(config Config, args ...string) ([]byte, error) {
+	args = append(append([]string{}, config.RgArgs...), args...)
+	if config.MaxDepth > 0 {
+		if searchDepth(config) < 1 {
+			return nil, nil
+		}
+		args = append(args, "--max-depth", strconv.Itoa(searchDepth(config)))
+	}
+	if config.Follow {
+		args = append(args, "--follow")
+	}
+	if config.Multiline {
+		args = append(args, "--multiline")
+	}
+	if config.Hidden {
+		args = append(args, "--hidden")
+		for _, excluded := range config.HiddenExclude {
+			args = append(args, "--glob", "!"+excluded)
+		}
+	}
+	for _, excluded := range config.Exclude {
+		args = append(args, "--glob", "!"+excluded)
+	}
+	if len(config.Folders) > 0 {
+		args = append(append(args, "--"), foldersWithinDepth(config)...)
+	}
+	command := exec.Command("/usr/local/bin/rg", args...)
+	command.Dir = config.directory
+	out, err := commandOutput(command)
+	// rg exits with 1 when nothing matches, which is fine by us, and with 2
+	// when it couldn't search, like for a pattern or rgArgs it won't take
+	if exit, exited := err.(*exec.ExitError); exited && exit.ExitCode() == 1 {
+		return out, nil
+	} else if exited {
+		return nil, exitError(ExitBackend, "rg failed: %s", strings.TrimSpace(string(exit.Stderr)))
+	} else if err != nil {
+		return nil, commandError("rg", err)
+	}
+	return out, nil
+}
+
+// the files rg lists, one per line
+func ripgrepFiles(config Config, args ...string) (map[string]bool, error) {
+	out, err := ripgrep(config, args...)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, file := range strings.Split(string(out), "\n") {
+		if len(file) > 0 && withinDepth(file, config) {
+			files[file] = true
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,157 @@
+package osearch
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var fieldPattern = regexp.MustCompile(`(?:^|\s)(file|tag|path|sort):(?:"([^"]*)"|(\S+))`)
+
+// the file:, tag: and path: parts of a query, each of which a note has to
+// satisfy on top of the rest of the query. path: takes a folder of the vault.
+// sort: isn't a filter but orders the results as --sort does.
+type fieldFilters struct {
+	files []string
+	tags  []string
+	paths []string
+	sort  string
+}
+
+func (f fieldFilters) empty() bool {
+	return len(f.files) == 0 && len(f.tags) == 0 && len(f.paths) == 0
+}
+
+// extractFields pulls the file:, tag:, path: and sort: fields out of a query,
+// returning them and whatever is left over
+func extractFields(query string) (fieldFilters, string) {
+	var filters fieldFilters
+	rest := fieldPattern.ReplaceAllStringFunc(query, func(field string) string {
+		match := fieldPattern.FindStringSubmatch(field)
+		value := match[2] + match[3]
+		switch match[1] {
+		case "file":
+			filters.files = append(filters.files, value)
+		case "tag":
+			filters.tags = append(filters.tags, strings.TrimPrefix(value, "#"))
+		case "path":
+			filters.paths = append(filters.paths, strings.Trim(value, "/"))
+		case "sort":
+			filters.sort = value
+		}
+		return " "
+	})
+	return filters, strings.TrimSpace(rest)
+}
+
+func containsFolded(s string, substring string, caseMode string) bool {
+	caseSensitive := isCaseSensitive(substring, caseMode)
+	return strings.Contains(comparable(s, caseSensitive), comparable(substring, caseSensitive))
+}
+
+// whether filename passes every filter; tags are only read from the note
+// when there's a tag: filter
+func (f fieldFilters) matches(filename string, fullPath string, caseMode string) bool {
+	for _, file := range f.files {
+		if !containsFolded(withoutMd(filepath.Base(filename)), file, caseMode) {
+			return false
+		}
+	}
+	for _, path := range f.paths {
+		if !inFolder(filename, path) {
+			return false
+		}
+	}
+	if len(f.tags) == 0 {
+		return true
+	}
+
+	content, err := readNote(fullPath)
+	if err != nil {
+		return false
+	}
+	tags := noteTags(parseFrontmatter(string(content)))
+	for _, wanted := range f.tags {
+		if !hasTag(tags, wanted) {
+			return false
+		}
+	}
+	return true
+}
+
+// whether filename is somewhere under folder, ignoring case and accents as
+// the macOS file system does
+func inFolder(filename string, folder string) bool {
+	path := comparable(filepath.ToSlash(filepath.Clean(filename)), false)
+	folder = comparable(strings.Trim(filepath.ToSlash(filepath.Clean(folder)), "/"), false)
+	return folder == "." || strings.HasPrefix(path, folder+"/")
+}
+
+// whether filename is in one of folders, or there are no folders to be in
+func inFolders(filename string, folders []string) bool {
+	if len(folders) == 0 {
+		return true
+	}
+	for _, folder := range folders {
+		if inFolder(filename, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// whether the vault in directory has folder in it
+func hasFolder(directory string, folder string) bool {
+	info, err := os.Stat(filepath.Join(directory, strings.Trim(folder, "/")))
+	return err == nil && info.IsDir()
+}
+
+// the folders fd and rg need to look in to find everything under paths,
+// or none for the whole vault
+func searchRoots(paths []string, directory string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	var roots []string
+	for _, path := range paths {
+		info, err := os.Stat(filepath.Join(directory, path))
+		if err != nil || !info.IsDir() {
+			// not a folder as typed, so leave it to the filter
+			return nil
+		}
+		// with more than one, files have to be in the innermost
+		if len(roots) == 0 || inFolder(path, roots[0]) {
+			roots = []string{path}
+		} else if !inFolder(roots[0], path) {
+			// in two unrelated folders at once; nothing will match
+			return []string{path}
+		}
+	}
+	return roots
+}
+
+// tags are case-insensitive, and tag:work also finds #work/meetings
+func hasTag(tags []string, wanted string) bool {
+	wanted = strings.ToLower(wanted)
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if tag == wanted || strings.HasPrefix(tag, wanted+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func withFields(results []AlfredResult, filters fieldFilters, caseMode string) []AlfredResult {
+	if filters.empty() {
+		return results
+	}
+	var kept []AlfredResult
+	for _, result := range results {
+		if filters.matches(result.Variables["path"], result.Variables["fullpath"], caseMode) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,85 @@
+package osearch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// accented letters and the plain letter they fold to
+var accentedLetters = map[rune]string{
+	'a': "àáâãäåāăą", 'A': "ÀÁÂÃÄÅĀĂĄ",
+	'c': "çćĉċč", 'C': "ÇĆĈĊČ",
+	'd': "ďđ", 'D': "ĎĐ",
+	'e': "èéêëēĕėęě", 'E': "ÈÉÊËĒĔĖĘĚ",
+	'g': "ĝğġģ", 'G': "ĜĞĠĢ",
+	'h': "ĥħ", 'H': "ĤĦ",
+	'i': "ìíîïĩīĭįı", 'I': "ÌÍÎÏĨĪĬĮİ",
+	'j': "ĵ", 'J': "Ĵ",
+	'k': "ķ", 'K': "Ķ",
+	'l': "ĺļľŀł", 'L': "ĹĻĽĿŁ",
+	'n': "ñńņňŉ", 'N': "ÑŃŅŇ",
+	'o': "òóôõöøōŏő", 'O': "ÒÓÔÕÖØŌŎŐ",
+	'r': "ŕŗř", 'R': "ŔŖŘ",
+	's': "śŝşšș", 'S': "ŚŜŞŠȘ",
+	't': "ţťŧț", 'T': "ŢŤŦȚ",
+	'u': "ùúûüũūŭůűų", 'U': "ÙÚÛÜŨŪŬŮŰŲ",
+	'w': "ŵ", 'W': "Ŵ",
+	'y': "ýÿŷ", 'Y': "ÝŸŶ",
+	'z': "źżž", 'Z': "ŹŻŽ",
+}
+
+var foldedLetters = make(map[rune]rune)
+
+func init() {
+	for plain, accented := range accentedLetters {
+		for _, letter := range accented {
+			foldedLetters[letter] = plain
+		}
+	}
+}
+
+// foldDiacritics strips accents, whether they're part of the letter (as
+// typed) or a separate combining mark (as macOS stores file names), so
+// "Café" in either form becomes "Cafe"
+func foldDiacritics(s string) string {
+	var folded strings.Builder
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if plain, ok := foldedLetters[r]; ok {
+			r = plain
+		}
+		folded.WriteRune(r)
+	}
+	return folded.String()
+}
+
+// a regex for one letter that also matches it with any accent, composed or
+// not. Letters without a plain form to fold to, like kana or Hangul, match
+// themselves either composed or decomposed.
+func foldingLetterPattern(letter rune) string {
+	plain := letter
+	if folded, ok := foldedLetters[letter]; ok {
+		plain = folded
+	}
+	accented, ok := accentedLetters[plain]
+	if !ok {
+		if decomposed := decomposeLetter(letter); decomposed != string(letter) {
+			return "(?:" + regexp.QuoteMeta(string(letter)) + "|" + regexp.QuoteMeta(decomposed) + ")"
+		}
+		return regexp.QuoteMeta(string(letter))
+	}
+	return "[" + string(plain) + accented + `]\p{Mn}*`
+}
+
+// foldingPattern turns text into a regex matching it literally, but
+// regardless of accents, so "cafe" finds "Café"
+func foldingPattern(text string) string {
+	var pattern strings.Builder
+	for _, r := range foldDiacritics(text) {
+		pattern.WriteString(foldingLetterPattern(r))
+	}
+	return pattern.String()
+}
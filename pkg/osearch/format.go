@@ -0,0 +1,224 @@
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the launchers results can be written out for
+const (
+	FormatAlfred  = "alfred"
+	FormatRaycast = "raycast"
+	// LaunchBar script output
+	FormatLaunchBar = "launchbar"
+	// a Lua table for a Hammerspoon chooser
+	FormatLua = "lua"
+	// a plain JSON list for anything else
+	FormatJson = "json"
+	// one line per result, for reading in a terminal
+	FormatPlain = "plain"
+	// tab-separated columns, for awk and friends
+	FormatTsv = "tsv"
+	// a json object per line, written as each result is found
+	FormatJsonLines = "jsonl"
+)
+
+// a result as a Raycast extension or script command lists it
+type RaycastItem struct {
+	ID       string `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Url      string `json:"url,omitempty"`
+}
+
+type RaycastResults struct {
+	Items []RaycastItem `json:"items"`
+}
+
+// a result as a LaunchBar action script returns it
+type LaunchBarItem struct {
+	Title        string `json:"title"`
+	Subtitle     string `json:"subtitle,omitempty"`
+	Url          string `json:"url,omitempty"`
+	QuickLookUrl string `json:"quickLookURL,omitempty"`
+	Icon         string `json:"icon,omitempty"`
+}
+
+// a result with just what's needed to act on it, for tools with no format
+// of their own
+type PlainResult struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Path     string `json:"path,omitempty"`
+	FullPath string `json:"fullpath,omitempty"`
+	Url      string `json:"url,omitempty"`
+	Line     string `json:"line,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+func plainResult(result AlfredResult) PlainResult {
+	plain := PlainResult{
+		Title:    result.Title,
+		Subtitle: result.Subtitle,
+		Path:     result.Variables["path"],
+		FullPath: result.Variables["fullpath"],
+		Url:      result.Arg,
+		Line:     result.Variables["line"],
+	}
+	if len(plain.Line) > 0 && result.Text != nil {
+		plain.Text = result.Text.LargeType
+	}
+	return plain
+}
+
+// WriteResults writes results to out in format, one of the Format constants
+func WriteResults(out io.Writer, results AlfredResults, format string) error {
+	switch format {
+	case FormatAlfred, "":
+		return writeJson(out, results)
+	case FormatRaycast:
+		items := []RaycastItem{}
+		for _, result := range results.Items {
+			items = append(items, RaycastItem{
+				ID:       result.UID,
+				Title:    result.Title,
+				Subtitle: result.Subtitle,
+				Path:     result.Variables["fullpath"],
+				Url:      result.Arg,
+			})
+		}
+		return writeJson(out, RaycastResults{Items: items})
+	case FormatLaunchBar:
+		items := []LaunchBarItem{}
+		for _, result := range results.Items {
+			item := LaunchBarItem{Title: result.Title, Subtitle: result.Subtitle, Url: result.Arg}
+			if fullPath := result.Variables["fullpath"]; len(fullPath) > 0 {
+				item.QuickLookUrl = fileUrl(fullPath)
+				// LaunchBar takes a bundle identifier for an app's icon
+				item.Icon = "md.obsidian"
+			}
+			items = append(items, item)
+		}
+		return writeJson(out, items)
+	case FormatLua:
+		return writeLua(out, results)
+	case FormatJson:
+		items := []PlainResult{}
+		for _, result := range results.Items {
+			items = append(items, plainResult(result))
+		}
+		return writeJson(out, items)
+	case FormatJsonLines:
+		for _, result := range results.Items {
+			err := WriteJsonLine(out, result)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatPlain, FormatTsv:
+		return writeLines(out, results, format)
+	}
+	return fmt.Errorf("can't write results as %s", format)
+}
+
+// WriteJsonLine writes result to out as a line of --format jsonl, for
+// streaming each result as Options.Found gets it
+func WriteJsonLine(out io.Writer, result AlfredResult) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(plainResult(result))
+}
+
+func writeJson(out io.Writer, v interface{}) error {
+	jsonResults, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	// unescape the stupid ampersand
+	jsonResults = []byte(strings.Replace(string(jsonResults), "\\u0026", "&", -1))
+	_, err = fmt.Fprintln(out, string(jsonResults))
+	return err
+}
+
+// tabs and line breaks would split a column or a row
+var tsvEscaper = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+// one line per result: the note's path (with the line number for --grep
+// matches), its title and the matched line. tsv gives every column, empty
+// or not; plain leaves out what's empty and runs the path and line number
+// together the way grep does
+func writeLines(out io.Writer, results AlfredResults, format string) error {
+	for _, result := range results.Items {
+		plain := plainResult(result)
+		if len(plain.FullPath) == 0 {
+			continue
+		}
+		var line string
+		if format == FormatTsv {
+			columns := []string{plain.FullPath, plain.Line, plain.Title, plain.Text}
+			for index, column := range columns {
+				columns[index] = tsvEscaper.Replace(column)
+			}
+			line = strings.Join(columns, "\t")
+		} else {
+			line = plain.FullPath
+			if len(plain.Line) > 0 {
+				line += ":" + plain.Line
+			}
+			line += "  " + plain.Title
+			if text := strings.Join(strings.Fields(plain.Text), " "); len(text) > 0 {
+				line += "  " + text
+			}
+		}
+		_, err := fmt.Fprintln(out, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// results as Lua source returning a list of tables, in the shape
+// Hammerspoon's hs.chooser takes choices
+func writeLua(out io.Writer, results AlfredResults) error {
+	var lua strings.Builder
+	lua.WriteString("return {\n")
+	for _, result := range results.Items {
+		plain := plainResult(result)
+		lua.WriteString(fmt.Sprintf("  {text = %s, subText = %s, path = %s, fullpath = %s, url = %s",
+			luaString(plain.Title), luaString(plain.Subtitle), luaString(plain.Path), luaString(plain.FullPath), luaString(plain.Url)))
+		if len(plain.Line) > 0 {
+			lua.WriteString(fmt.Sprintf(", line = %s, matched = %s", plain.Line, luaString(plain.Text)))
+		}
+		lua.WriteString("},\n")
+	}
+	lua.WriteString("}\n")
+	_, err := io.WriteString(out, lua.String())
+	return err
+}
+
+// a quoted Lua string literal; Lua has no \u escapes before 5.3, so only
+// quotes, backslashes and control characters are escaped
+func luaString(s string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			quoted.WriteRune('\\')
+			quoted.WriteRune(r)
+		case r == '\n':
+			quoted.WriteString("\\n")
+		case r < ' ' || r == 0x7f:
+			quoted.WriteString(fmt.Sprintf("\\%03d", r))
+		default:
+			quoted.WriteRune(r)
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
@@ -0,0 +1,95 @@
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// how many of the latest visits to a note are remembered
+const MaxVisits = 10
+
+// a visit's weight halves every this many days
+const VisitHalfLifeDays = 14
+
+// when each note was opened from a result, keyed by result uid, oldest first
+type Visits map[string][]int64
+
+func visitsFile() string {
+	return filepath.Join(dataDir(), "visits.json")
+}
+
+func loadVisits() Visits {
+	visits := make(Visits)
+	content, err := ioutil.ReadFile(visitsFile())
+	if err != nil {
+		return visits
+	}
+	err = json.Unmarshal(content, &visits)
+	if err != nil {
+		log.Printf("ignoring unreadable %s: %s", visitsFile(), err)
+		return make(Visits)
+	}
+	return visits
+}
+
+func saveVisits(visits Visits) error {
+	err := os.MkdirAll(dataDir(), 0700)
+	if err != nil {
+		return fmt.Errorf("could not create %s: %w", dataDir(), err)
+	}
+	content, _ := json.Marshal(visits)
+	// write then rename so a concurrent search never reads half a file
+	temp := visitsFile() + ".tmp"
+	err = ioutil.WriteFile(temp, content, 0600)
+	if err == nil {
+		err = os.Rename(temp, visitsFile())
+	}
+	if err != nil {
+		return fmt.Errorf("could not save %s: %w", visitsFile(), err)
+	}
+	return nil
+}
+
+func (visits Visits) record(uid string, now time.Time) {
+	times := append(visits[uid], now.Unix())
+	if len(times) > MaxVisits {
+		times = times[len(times)-MaxVisits:]
+	}
+	visits[uid] = times
+}
+
+// frequently and recently opened notes score highest
+func (visits Visits) frecency(uid string, now time.Time) float64 {
+	score := 0.0
+	for _, visit := range visits[uid] {
+		ageDays := now.Sub(time.Unix(visit, 0)).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		score += math.Pow(0.5, ageDays/VisitHalfLifeDays)
+	}
+	return score
+}
+
+// put the notes opened most often and most recently first, otherwise
+// keeping the order results came in
+func sortByFrecency(results []AlfredResult, visits Visits) {
+	now := time.Now()
+	sort.SliceStable(results, func(i, j int) bool {
+		return visits.frecency(results[i].UID, now) > visits.frecency(results[j].UID, now)
+	})
+}
+
+// RecordVisit notes that the note at path in vault was just opened
+func RecordVisit(path string, vault string) error {
+	visits := loadVisits()
+	visits.record(resultUid(path, vault), time.Now())
+	return saveVisits(visits)
+}
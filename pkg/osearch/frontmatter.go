@@ -0,0 +1,87 @@
+package osearch
+
+import (
+	"regexp"
+	"strings"
+)
+
+var inlineTagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+
+// parseFrontmatter splits a note into its YAML frontmatter and body. Only the
+// subset of YAML that Obsidian writes is understood: scalars, flow lists and
+// block lists. Every value comes back as a list.
+func parseFrontmatter(content string) (map[string][]string, string) {
+	fields := make(map[string][]string)
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return fields, content
+	}
+
+	lines := strings.Split(content, "\n")
+	var key string
+	for index := 1; index < len(lines); index++ {
+		line := strings.TrimRight(lines[index], "\r")
+		if line == "---" || line == "..." {
+			return fields, strings.Join(lines[index+1:], "\n")
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") && len(key) > 0 {
+			fields[key] = append(fields[key], unquote(trimmed[2:]))
+			continue
+		}
+
+		colon := strings.Index(line, ":")
+		if colon <= 0 || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := strings.TrimSpace(line[colon+1:])
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquote(item); len(item) > 0 {
+					fields[key] = append(fields[key], item)
+				}
+			}
+		} else if len(value) > 0 {
+			fields[key] = append(fields[key], unquote(value))
+		}
+	}
+
+	// never closed, so it wasn't frontmatter after all
+	return make(map[string][]string), content
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// noteTags collects the tags declared in frontmatter and inline in the body,
+// without the leading #
+func noteTags(frontmatter map[string][]string, body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if len(tag) > 0 && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, key := range []string{"tags", "tag"} {
+		for _, value := range frontmatter[key] {
+			for _, tag := range strings.Fields(value) {
+				add(tag)
+			}
+		}
+	}
+	for _, match := range inlineTagPattern.FindAllStringSubmatch(body, -1) {
+		add(match[1])
+	}
+	return tags
+}
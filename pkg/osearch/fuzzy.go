@@ -0,0 +1,145 @@
+package osearch
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// scoring in the spirit of fzf: every matched character is worth something,
+// more at the start of words and path segments and in unbroken runs, and
+// skipping characters between matches costs a little
+const (
+	fuzzyMatch            = 16
+	fuzzySegmentBonus     = 10
+	fuzzyBoundaryBonus    = 8
+	fuzzyCamelBonus       = 7
+	fuzzyConsecutiveBonus = 6
+	fuzzyFilenameBonus    = 2
+	fuzzyGapStart         = 3
+	fuzzyGapExtension     = 1
+)
+
+const noMatch = -1 << 30
+
+func isWordSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("/_-.()[]", r)
+}
+
+func fuzzyBonus(candidate []rune, index int, filenameStart int) int {
+	bonus := 0
+	if index >= filenameStart {
+		bonus += fuzzyFilenameBonus
+	}
+	if index == 0 {
+		return bonus + fuzzySegmentBonus
+	}
+	previous := candidate[index-1]
+	switch {
+	case previous == '/':
+		bonus += fuzzySegmentBonus
+	case isWordSeparator(previous):
+		bonus += fuzzyBoundaryBonus
+	case unicode.IsLower(previous) && unicode.IsUpper(candidate[index]):
+		bonus += fuzzyCamelBonus
+	case unicode.IsLetter(previous) && unicode.IsDigit(candidate[index]):
+		bonus += fuzzyCamelBonus
+	}
+	return bonus
+}
+
+// fuzzyScore reports whether every character of pattern appears in candidate
+// in order, and how good the best such alignment is. Spaces in the pattern
+// are ignored.
+func fuzzyScore(pattern string, candidate string, caseSensitive bool) (int, bool) {
+	pattern = foldDiacritics(strings.Replace(pattern, " ", "", -1))
+	candidate = foldDiacritics(candidate)
+	haystack := []rune(candidate)
+	lower := haystack
+	if !caseSensitive {
+		pattern = strings.ToLower(pattern)
+		lower = []rune(strings.ToLower(candidate))
+	}
+	needle := []rune(pattern)
+	if len(needle) == 0 {
+		return 0, true
+	}
+	if len(needle) > len(lower) || len(lower) != len(haystack) {
+		return 0, false
+	}
+
+	filenameStart := strings.LastIndex(candidate, "/") + 1
+	filenameStart = len([]rune(candidate[:filenameStart]))
+
+	// previous[j] is the best score with the last pattern character so far
+	// matched exactly at position j
+	previous := make([]int, len(lower))
+	current := make([]int, len(lower))
+	for j := range lower {
+		previous[j] = noMatch
+		if lower[j] == needle[0] {
+			previous[j] = fuzzyMatch + fuzzyBonus(haystack, j, filenameStart)
+		}
+	}
+
+	for i := 1; i < len(needle); i++ {
+		// the best earlier match to jump from, less the cost of the gap
+		bestGap := noMatch
+		for j := range lower {
+			current[j] = noMatch
+			if j > 0 && lower[j] == needle[i] {
+				best := bestGap
+				if previous[j-1] != noMatch && previous[j-1]+fuzzyConsecutiveBonus > best {
+					best = previous[j-1] + fuzzyConsecutiveBonus
+				}
+				if best != noMatch {
+					current[j] = best + fuzzyMatch + fuzzyBonus(haystack, j, filenameStart)
+				}
+			}
+			if bestGap != noMatch {
+				bestGap -= fuzzyGapExtension
+			}
+			if previous[j] != noMatch && previous[j]-fuzzyGapStart > bestGap {
+				bestGap = previous[j] - fuzzyGapStart
+			}
+		}
+		previous, current = current, previous
+	}
+
+	best := noMatch
+	for _, score := range previous {
+		if score > best {
+			best = score
+		}
+	}
+	return best, best != noMatch
+}
+
+type fuzzyCandidate struct {
+	filename string
+	score    int
+}
+
+// the files matching pattern, best first, shorter paths winning ties
+func fuzzyFilter(pattern string, filenames []string, caseSensitive bool) []string {
+	var candidates []fuzzyCandidate
+	for _, filename := range filenames {
+		if score, ok := fuzzyScore(pattern, withoutMd(filename), caseSensitive); ok {
+			score += abbreviationScore(pattern, withoutMd(filepath.Base(filename)))
+			candidates = append(candidates, fuzzyCandidate{filename, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return len(candidates[i].filename) < len(candidates[j].filename)
+	})
+
+	matches := make([]string, len(candidates))
+	for index, candidate := range candidates {
+		matches[index] = candidate.filename
+	}
+	return matches
+}
@@ -0,0 +1,112 @@
+package osearch
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Graphviz's language, which graphs can be written in as well as JSON
+const FormatDot = "dot"
+
+// Graph is how the notes in a vault link to each other
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a note, or an attachment a note links to
+type GraphNode struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// GraphEdge is the links from one file to another, however many there are
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Links int    `json:"links"`
+}
+
+// LinkGraph reads every note in the vault at directory for its links.
+// Links to notes that don't exist are left out. "backends" can pick a
+// backend for it as "graph".
+func LinkGraph(directory string, config Config) (Graph, error) {
+	files, err := vaultFiles(directory, "graph", config)
+	if err != nil {
+		return Graph{}, err
+	}
+	resolver := newLinkResolver(files)
+
+	nodes := make(map[string]bool)
+	edges := make(map[[2]string]int)
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".md") {
+			continue
+		}
+		nodes[file] = true
+		content, err := readNote(filepath.Join(directory, file))
+		if err != nil {
+			continue
+		}
+		for _, link := range noteLinks(string(content)) {
+			if target, ok := resolver.resolve(link, file); ok {
+				nodes[target] = true
+				edges[[2]string{file, target}]++
+			}
+		}
+	}
+
+	var graph Graph
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: node, Title: withoutMd(filepath.Base(node))})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].ID < graph.Nodes[j].ID
+	})
+	for edge, links := range edges {
+		graph.Edges = append(graph.Edges, GraphEdge{From: edge[0], To: edge[1], Links: links})
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+// Write writes the graph as Graphviz DOT or as JSON
+func (graph Graph) Write(out io.Writer, format string) error {
+	switch format {
+	case FormatDot:
+		return graph.writeDot(out)
+	case FormatJson:
+		return writeJson(out, graph)
+	}
+	return fmt.Errorf("can't write a graph as %s", format)
+}
+
+func (graph Graph) writeDot(out io.Writer) error {
+	var dot strings.Builder
+	dot.WriteString("digraph vault {\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&dot, "  %s [label=%s];\n", dotString(node.ID), dotString(node.Title))
+	}
+	for _, edge := range graph.Edges {
+		if edge.Links > 1 {
+			fmt.Fprintf(&dot, "  %s -> %s [weight=%d];\n", dotString(edge.From), dotString(edge.To), edge.Links)
+		} else {
+			fmt.Fprintf(&dot, "  %s -> %s;\n", dotString(edge.From), dotString(edge.To))
+		}
+	}
+	dot.WriteString("}\n")
+	_, err := io.WriteString(out, dot.String())
+	return err
+}
+
+func dotString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,86 @@
+package osearch
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// graphemes splits s into what reads as single characters, so cutting
+// text short never separates a letter from its accent or breaks up an
+// emoji built from several code points: a family joined with zero width
+// joiners, a flag's pair of regional indicators, or a skin tone or
+// variation selector after its emoji. It's a simplification of Unicode's
+// grapheme cluster rules that covers what turns up in notes.
+func graphemes(s string) []string {
+	var clusters []string
+	start := 0
+	var previous rune
+	// regional indicators seen in a row, which pair up into flags
+	indicators := 0
+	for index, r := range s {
+		if index > 0 && !extendsGrapheme(previous, r, indicators) {
+			clusters = append(clusters, s[start:index])
+			start = index
+			indicators = 0
+		}
+		if isRegionalIndicator(r) {
+			indicators++
+		}
+		previous = r
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// whether r belongs with the character before it, previous
+func extendsGrapheme(previous rune, r rune, indicators int) bool {
+	switch {
+	case previous == '\r' && r == '\n':
+		return true
+	case previous == zeroWidthJoiner:
+		return true
+	case r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+		// variation selectors, skin tones and the tags of subdivision flags
+		return true
+	case isRegionalIndicator(previous) && isRegionalIndicator(r):
+		return indicators%2 == 1
+	case isHangulFollower(r) && isHangulJamo(previous):
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// how many characters s reads as
+func graphemeCount(s string) int {
+	if isAscii(s) {
+		return len(s)
+	}
+	return len(graphemes(s))
+}
+
+func isAscii(s string) bool {
+	for index := 0; index < len(s); index++ {
+		if s[index] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// whether a character is a space
+func isSpaceGrapheme(cluster string) bool {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,110 @@
+package osearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// searches typed this soon after the one before are taken to be the same
+// search still being typed
+const typingSeconds = 30
+
+type pastSearch struct {
+	Query string `json:"query"`
+	Time  int64  `json:"time"`
+}
+
+// the latest searches of each mode, newest first
+type searchHistory map[string][]pastSearch
+
+func historyFile() string {
+	return filepath.Join(dataDir(), "history.json")
+}
+
+func loadHistory() searchHistory {
+	history := make(searchHistory)
+	content, err := ioutil.ReadFile(historyFile())
+	if err != nil {
+		return history
+	}
+	err = json.Unmarshal(content, &history)
+	if err != nil {
+		log.Printf("ignoring unreadable %s: %s", historyFile(), err)
+		return make(searchHistory)
+	}
+	return history
+}
+
+func saveHistory(history searchHistory) {
+	err := os.MkdirAll(dataDir(), 0700)
+	if err != nil {
+		log.Printf("could not create %s", dataDir())
+		return
+	}
+	content, _ := json.Marshal(history)
+	temp := historyFile() + ".tmp"
+	err = ioutil.WriteFile(temp, content, 0600)
+	if err == nil {
+		err = os.Rename(temp, historyFile())
+	}
+	if err != nil {
+		log.Printf("could not save %s: %s", historyFile(), err)
+	}
+}
+
+// remember a search, keeping the newest size of them. Alfred searches on
+// every keystroke, so a query that carries on typing (or backspacing) the
+// latest one replaces it rather than piling up its prefixes.
+func (history searchHistory) record(mode string, query string, size int, now time.Time) {
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return
+	}
+	searches := history[mode]
+	if len(searches) > 0 {
+		latest := searches[0]
+		typing := now.Unix()-latest.Time <= typingSeconds
+		if typing && (strings.HasPrefix(query, latest.Query) || strings.HasPrefix(latest.Query, query)) {
+			searches = searches[1:]
+		}
+	}
+	kept := []pastSearch{{Query: query, Time: now.Unix()}}
+	for _, earlier := range searches {
+		if earlier.Query != query && len(kept) < size {
+			kept = append(kept, earlier)
+		}
+	}
+	history[mode] = kept
+}
+
+// add a search to the history if the config keeps one
+func recordSearch(mode string, query string, config Config) {
+	if config.History <= 0 {
+		return
+	}
+	history := loadHistory()
+	history.record(mode, query, config.History, time.Now())
+	saveHistory(history)
+}
+
+// the latest searches as items that fill them back in, so Alfred runs them
+// again
+func historyResults(searches []pastSearch) AlfredResults {
+	results := AlfredResults{Items: []AlfredResult{}, SkipKnowledge: true}
+	for _, search := range searches {
+		valid := false
+		results.Items = append(results.Items, AlfredResult{
+			Type:         "default",
+			Valid:        &valid,
+			Title:        search.Query,
+			Subtitle:     "Search again · " + time.Unix(search.Time, 0).Format("2 Jan 15:04"),
+			Autocomplete: search.Query,
+		})
+	}
+	return results
+}
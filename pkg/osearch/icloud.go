@@ -0,0 +1,103 @@
+package osearch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// where iCloud Drive keeps what it syncs, Obsidian's vaults included
+const iCloudFolder = "/Library/Mobile Documents/"
+
+// whether the vault in directory is synced by iCloud Drive, which leaves a
+// placeholder in place of each note it has evicted to save space
+func isICloudVault(directory string) bool {
+	absolute, err := filepath.Abs(directory)
+	return err == nil && strings.Contains(absolute+"/", iCloudFolder)
+}
+
+// the name of the note an iCloud placeholder stands in for: Note.md is
+// kept as .Note.md.icloud until it's downloaded again
+func placeholderFor(name string) (string, bool) {
+	if len(name) <= len("..icloud") || !strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".icloud") {
+		return "", false
+	}
+	return strings.TrimSuffix(name[1:], ".icloud"), true
+}
+
+// whether a file named name is an iCloud placeholder
+func isPlaceholder(name string) bool {
+	_, ok := placeholderFor(name)
+	return ok
+}
+
+// the placeholder iCloud keeps for the note at path
+func placeholderPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".icloud")
+}
+
+// the files of a listing whose names match pattern, followed by every
+// placeholder in it when config asks for those, which are matched later by
+// the names of their notes
+func namesAndPlaceholders(files []string, pattern string, config Config) ([]string, error) {
+	if !config.placeholders {
+		return namesMatching(files, pattern)
+	}
+	files, placeholders := splitPlaceholders(files)
+	matched, err := namesMatching(files, pattern)
+	return append(matched, placeholders...), err
+}
+
+// split the placeholders off the rest of files
+func splitPlaceholders(files []string) ([]string, []string) {
+	var kept, placeholders []string
+	for _, file := range files {
+		if isPlaceholder(filepath.Base(file)) {
+			placeholders = append(placeholders, file)
+		} else {
+			kept = append(kept, file)
+		}
+	}
+	return kept, placeholders
+}
+
+// the notes placeholders stand in for whose names match pattern, by the
+// paths they'll have once downloaded, so they show up in searches by name.
+// Their contents can't be searched until they're downloaded.
+func evictedNotesMatching(placeholders []string, pattern string, config Config) ([]string, error) {
+	var notes []string
+	for _, placeholder := range placeholders {
+		name, _ := placeholderFor(filepath.Base(placeholder))
+		note := filepath.Join(filepath.Dir(placeholder), name)
+		if withinDepth(note, config) && !isExcluded(note, config.Exclude) {
+			notes = append(notes, note)
+		}
+	}
+	return namesMatching(notes, pattern)
+}
+
+// have iCloud Drive start downloading the evicted notes among the results
+// a search shows
+func downloadEvicted(results []AlfredResult) {
+	for _, result := range results {
+		if fullPath := result.Variables["fullpath"]; len(fullPath) > 0 && isEvicted(fullPath) {
+			downloadNote(fullPath)
+		}
+	}
+}
+
+// ask iCloud Drive to bring back the evicted note at fullPath, without
+// waiting for it
+func downloadNote(fullPath string) {
+	err := exec.Command("brctl", "download", placeholderPath(fullPath)).Start()
+	if err != nil {
+		Debug("icloud download", "path", fullPath, "error", err.Error())
+	}
+}
+
+// whether the note at fullPath is still waiting in iCloud
+func isEvicted(fullPath string) bool {
+	_, err := os.Stat(placeholderPath(fullPath))
+	return err == nil
+}
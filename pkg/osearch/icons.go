@@ -0,0 +1,57 @@
+package osearch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const ObsidianApp = "/Applications/Obsidian.app"
+
+const AlertIcon = "/System/Library/CoreServices/CoreTypes.bundle/Contents/Resources/AlertStopIcon.icns"
+
+type AlfredIcon struct {
+	Type string `json:"type,omitempty"`
+	Path string `json:"path"`
+}
+
+// notes get the Obsidian icon, everything else the icon macOS would give it,
+// unless the user has configured an icon for the folder it lives in
+func resultIcon(filename string, directory string, config Config) *AlfredIcon {
+	if icon := folderIcon(filename, config.FolderIcons); len(icon) > 0 {
+		return &AlfredIcon{Path: ExpandHome(icon)}
+	}
+
+	if isImage(filename) {
+		return &AlfredIcon{Type: "filetype", Path: "public.image"}
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md":
+		return &AlfredIcon{Type: "fileicon", Path: ObsidianApp}
+	case ".pdf":
+		return &AlfredIcon{Type: "filetype", Path: "com.adobe.pdf"}
+	case ".mp3", ".m4a", ".wav", ".ogg", ".flac", ".webm", ".3gp":
+		return &AlfredIcon{Type: "filetype", Path: "public.audio"}
+	case ".mp4", ".mov", ".mkv", ".ogv":
+		return &AlfredIcon{Type: "filetype", Path: "public.movie"}
+	}
+	// canvases and the rest carry the document icon Finder shows for them
+	return &AlfredIcon{Type: "fileicon", Path: filepath.Join(directory, filename)}
+}
+
+// the icon configured for the deepest folder containing filename
+func folderIcon(filename string, folderIcons map[string]string) string {
+	var icon string
+	longest := -1
+	folder := filepath.ToSlash(filepath.Dir(filename))
+	for prefix, path := range folderIcons {
+		prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+		if folder == prefix || strings.HasPrefix(folder, prefix+"/") {
+			if len(prefix) > longest {
+				icon = path
+				longest = len(prefix)
+			}
+		}
+	}
+	return icon
+}
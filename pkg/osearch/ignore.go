@@ -0,0 +1,126 @@
+package osearch
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// whether filename is one of the ignored notes or inside an ignored folder
+func isIgnored(filename string, ignored []string) bool {
+	filename = filepath.ToSlash(filepath.Clean(filename))
+	for _, ignore := range ignored {
+		ignore = strings.Trim(filepath.ToSlash(filepath.Clean(ignore)), "/")
+		if filename == ignore || strings.HasPrefix(filename, ignore+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// whether filename, or a folder it's in, matches one of globs the way fd's
+// --exclude and rg's --glob do: a glob with no slash in it matches a name
+// anywhere in the vault, one with a slash matches from the top of the vault,
+// and ** stands for any number of folders
+func isExcluded(filename string, globs []string) bool {
+	if len(globs) == 0 {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(filename)), "/")
+	for _, glob := range globs {
+		pattern := globPattern(glob)
+		if pattern == nil {
+			continue
+		}
+		anchored := strings.Contains(strings.TrimSuffix(glob, "/"), "/")
+		for index := range parts {
+			candidate := parts[index]
+			if anchored {
+				candidate = strings.Join(parts[:index+1], "/")
+			}
+			if pattern.MatchString(candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// a glob as a regular expression matching all of a path, or nil if it
+// makes no sense
+func globPattern(glob string) *regexp.Regexp {
+	glob = strings.TrimPrefix(strings.TrimSuffix(filepath.ToSlash(glob), "/"), "/")
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for index := 0; index < len(glob); index++ {
+		c := glob[index]
+		switch {
+		case strings.HasPrefix(glob[index:], "**/"):
+			pattern.WriteString("(?:.*/)?")
+			index += 2
+		case strings.HasPrefix(glob[index:], "**"):
+			pattern.WriteString(".*")
+			index++
+		case c == '*':
+			pattern.WriteString("[^/]*")
+		case c == '?':
+			pattern.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[index+1:], ']')
+			if end < 0 {
+				pattern.WriteString(`\[`)
+				continue
+			}
+			class := glob[index+1 : index+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			pattern.WriteString("[" + class + "]")
+			index += end + 1
+		case c == '\\' && index+1 < len(glob):
+			index++
+			pattern.WriteString(regexp.QuoteMeta(glob[index : index+1]))
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	pattern.WriteString("$")
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil
+	}
+	return compiled
+}
+
+func withoutIgnored(results []AlfredResult, ignored []string) []AlfredResult {
+	if len(ignored) == 0 {
+		return results
+	}
+	var kept []AlfredResult
+	for _, result := range results {
+		if !isIgnored(result.Variables["path"], ignored) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// Ignore adds the note or folder at path to the ignore list in the config
+// file, or with remove takes it off
+func Ignore(configFile string, path string, remove bool) error {
+	path = filepath.Clean(path)
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	ignored := []string{}
+	for _, ignore := range config.Ignore {
+		if filepath.Clean(ignore) != path {
+			ignored = append(ignored, ignore)
+		}
+	}
+	if !remove {
+		ignored = append(ignored, path)
+	}
+	return updateConfig(configFile, "ignore", ignored)
+}
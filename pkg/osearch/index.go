@@ -0,0 +1,517 @@
+package osearch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// bumped whenever what's saved changes, so an old index is rebuilt rather
+// than misread
+const indexVersion = 6
+
+// indexBackend answers from a copy of the vault's text kept in the data
+// folder. Each search walks the vault to bring the copy up to date, but
+// only reads the notes that changed since the last one.
+type indexBackend struct {
+	index *noteIndex
+	// the text of each file unpacked so far
+	texts map[string]string
+}
+
+// noteIndex is kept in two files. A small one lists the files, and is read
+// in full; a data file beside it, with the text, trigrams and posting
+// lists, is mapped into memory, so only the parts a search looks at are
+// ever read from disk.
+type noteIndex struct {
+	Version int
+	// which data file goes with this list; each save writes a new one
+	Generation int64
+	Files      map[string]indexedFile
+	// for each trigram of text, the files that have it, as indexes into
+	// Paths in order: PostingKeys holds the trigrams in order, PostingStarts
+	// where each one's files begin in PostingIds
+	Paths         []string
+	PostingKeys   span
+	PostingStarts span
+	PostingIds    span
+
+	data []byte
+	// the text of files that changed, kept here rather than in data when
+	// the index couldn't be saved
+	fresh map[string]freshFile
+}
+
+// where something is in the data file
+type span struct {
+	Offset int64
+	Length int64
+}
+
+func (s span) of(data []byte) []byte {
+	if s.Offset < 0 || s.Offset+s.Length > int64(len(data)) {
+		return nil
+	}
+	return data[s.Offset : s.Offset+s.Length]
+}
+
+type indexedFile struct {
+	ModTime int64
+	Size    int64
+	// what the contents hash to, so a file whose time changed but whose
+	// contents didn't, as sync tools leave them, isn't taken in again
+	Hash [sha1.Size]byte
+	// binary files are only listed
+	Binary bool
+	// the text, deflated so the index takes a fraction of the vault's size
+	Packed span
+	// every trigram of the text, to rebuild the posting lists from
+	Trigrams span
+}
+
+func packText(text []byte) []byte {
+	var packed bytes.Buffer
+	writer, _ := flate.NewWriter(&packed, flate.DefaultCompression)
+	writer.Write(text)
+	writer.Close()
+	return packed.Bytes()
+}
+
+// the text of an indexed file, only unpacked once a search needs it
+func (backend *indexBackend) text(file string) string {
+	if text, ok := backend.texts[file]; ok {
+		return text
+	}
+	packed := backend.index.Files[file].Packed.of(backend.index.data)
+	if unsaved, ok := backend.index.fresh[file]; ok {
+		packed = unsaved.packed
+	}
+	content, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(packed)))
+	if err != nil {
+		log.Printf("could not unpack %s from the index: %s", file, err)
+	}
+	text := string(content)
+	backend.texts[file] = text
+	return text
+}
+
+func (backend *indexBackend) FindFiles(pattern string, config Config) ([]string, error) {
+	return namesAndPlaceholders(backend.files(config), pattern, config)
+}
+
+func (backend *indexBackend) FilesContaining(pattern string, config Config) (map[string]bool, error) {
+	re, err := compileBackendPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, file := range backend.candidates(pattern, config) {
+		if !backend.index.Files[file].Binary && hasMatch(backend.text(file), re, config) {
+			files[file] = true
+		}
+	}
+	return files, nil
+}
+
+func (backend *indexBackend) GrepContent(pattern string, config Config) ([]LineMatch, error) {
+	re, err := compileBackendPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var matches []LineMatch
+	for _, file := range backend.candidates(pattern, config) {
+		if !backend.index.Files[file].Binary {
+			matches = append(matches, grepText(file, backend.text(file), re, config)...)
+		}
+	}
+	return matches, nil
+}
+
+// the indexed files under the folders config searches, updating the index
+// the first time it's needed
+func (backend *indexBackend) files(config Config) []string {
+	if backend.index == nil {
+		index, err := updateIndex(config)
+		if err != nil {
+			log.Print(err)
+		}
+		backend.index = index
+		backend.texts = make(map[string]string)
+	}
+	var files []string
+	for file := range backend.index.Files {
+		if inFolders(file, config.Folders) && withinDepth(file, config) && (config.Hidden || !isHiddenPath(file) || config.placeholders && isPlaceholder(filepath.Base(file)) && !isHiddenPath(filepath.Dir(file))) && !isExcluded(file, config.Exclude) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// the files under the folders config searches that may match pattern: only
+// those with all the trigrams it's sure to need, so that only they are
+// unpacked and searched
+func (backend *indexBackend) candidates(pattern string, config Config) []string {
+	files := backend.files(config)
+	possible := backend.index.candidates(patternTrigrams(pattern))
+	if possible == nil {
+		return files
+	}
+	// the posting lists don't know about files the index couldn't save
+	for file := range backend.index.fresh {
+		possible[file] = true
+	}
+	var candidates []string
+	for _, file := range files {
+		if possible[file] {
+			candidates = append(candidates, file)
+		}
+	}
+	return candidates
+}
+
+// the files whose text has every one of trigrams, from the posting lists,
+// or nil when there are no trigrams to go on and every file is a candidate
+func (index *noteIndex) candidates(trigrams []uint64) map[string]bool {
+	if len(trigrams) == 0 {
+		return nil
+	}
+	var ids []int32
+	for position, trigram := range trigrams {
+		postings := index.postings(trigram)
+		if position == 0 {
+			ids = postings
+		} else {
+			ids = intersectIds(ids, postings)
+		}
+		if len(ids) == 0 {
+			break
+		}
+	}
+	files := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if int(id) < len(index.Paths) {
+			files[index.Paths[id]] = true
+		}
+	}
+	return files
+}
+
+// the files with trigram, found by a binary search of the mapped keys
+func (index *noteIndex) postings(trigram uint64) []int32 {
+	keys := index.PostingKeys.of(index.data)
+	starts := index.PostingStarts.of(index.data)
+	ids := index.PostingIds.of(index.data)
+	count := len(keys) / 8
+	found := sort.Search(count, func(i int) bool {
+		return binary.LittleEndian.Uint64(keys[i*8:]) >= trigram
+	})
+	if found == count || binary.LittleEndian.Uint64(keys[found*8:]) != trigram || len(starts) < (found+2)*4 {
+		return nil
+	}
+	start := binary.LittleEndian.Uint32(starts[found*4:])
+	end := binary.LittleEndian.Uint32(starts[(found+1)*4:])
+	if int(end)*4 > len(ids) || start > end {
+		return nil
+	}
+	postings := make([]int32, 0, end-start)
+	for position := start; position < end; position++ {
+		postings = append(postings, int32(binary.LittleEndian.Uint32(ids[position*4:])))
+	}
+	return postings
+}
+
+// where something kept about the vault in directory lives: in a folder of
+// the data folder for that kind of thing, named for the vault's real path
+func vaultDataFile(directory string, kind string, extension string) string {
+	if absolute, err := filepath.Abs(directory); err == nil {
+		directory = absolute
+	}
+	if real, err := filepath.EvalSymlinks(directory); err == nil {
+		directory = real
+	}
+	return filepath.Join(dataDir(), kind, fmt.Sprintf("%x.%s", sha1.Sum([]byte(directory)), extension))
+}
+
+// where the index of the vault in directory is kept
+func indexFile(directory string) string {
+	return vaultDataFile(directory, "index", "gob")
+}
+
+// the data file for a generation of the index
+func indexDataFile(directory string, generation int64) string {
+	return vaultDataFile(directory, "index", fmt.Sprintf("%d.data", generation))
+}
+
+func emptyIndex() *noteIndex {
+	return &noteIndex{Version: indexVersion, Files: make(map[string]indexedFile)}
+}
+
+// the saved index of the vault in directory, with its data file mapped in.
+// A save running alongside, like the service's, can replace the list and
+// remove the data file it pointed to in between reading one and mapping
+// the other, so a data file gone missing means reading the new list.
+func loadIndex(directory string) *noteIndex {
+	for attempt := 0; attempt < 3; attempt++ {
+		index := emptyIndex()
+		file, err := os.Open(indexFile(directory))
+		if err != nil {
+			return index
+		}
+		err = gob.NewDecoder(file).Decode(index)
+		file.Close()
+		if err != nil || index.Version != indexVersion {
+			// start again rather than trust half an index, or one written
+			// differently
+			return emptyIndex()
+		}
+		index.data, err = mapFile(indexDataFile(directory, index.Generation))
+		if err == nil {
+			return index
+		}
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	return emptyIndex()
+}
+
+// a file whose text changed since the index was saved
+type freshFile struct {
+	packed   []byte
+	trigrams []uint64
+}
+
+// the saved index brought up to date with the whole vault, saved again if
+// anything changed. When it can't be saved, the index is still returned
+// along with why, for this search to use.
+func updateIndex(config Config) (*noteIndex, error) {
+	index := loadIndex(config.directory)
+
+	// whether the data file needs writing again, or only the list
+	changed := false
+	touched := false
+	fresh := make(map[string]freshFile)
+	seen := make(map[string]bool)
+	for _, path := range walkVault(Config{Follow: config.Follow, Hidden: config.Hidden, HiddenExclude: config.HiddenExclude, directory: config.directory, placeholders: true}) {
+		seen[path] = true
+		info, err := os.Stat(config.vaultPath(path))
+		if err != nil {
+			continue
+		}
+		indexed, ok := index.Files[path]
+		if ok && indexed.ModTime == info.ModTime().UnixNano() && indexed.Size == info.Size() {
+			continue
+		}
+		content, isText := readText(config.vaultPath(path))
+		hash := sha1.Sum(content)
+		if ok && indexed.Hash == hash && indexed.Binary == !isText {
+			indexed.ModTime = info.ModTime().UnixNano()
+			index.Files[path] = indexed
+			touched = true
+			continue
+		}
+		index.Files[path] = indexedFile{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Hash: hash, Binary: !isText}
+		if isText {
+			fresh[path] = freshFile{packed: packText(content), trigrams: textTrigrams(string(content))}
+		}
+		changed = true
+	}
+	for path := range index.Files {
+		if !seen[path] {
+			delete(index.Files, path)
+			changed = true
+		}
+	}
+
+	if changed {
+		saved, err := saveIndex(config.directory, index, fresh)
+		if err != nil {
+			// this search can still use what it's read, and the next one
+			// will try saving it again
+			index.fresh = fresh
+			return index, fmt.Errorf("could not save the index: %w", err)
+		}
+		index = saved
+	} else if touched {
+		err := saveIndexList(config.directory, index)
+		if err != nil {
+			return index, fmt.Errorf("could not save the index: %w", err)
+		}
+	}
+	return index, nil
+}
+
+// write the index with the fresh files' text in place of what it had,
+// returning it as it'll be loaded next time. The data file is written
+// under a generation no other save has used, before the list that points
+// to it, so an interrupted save, or a search or save running alongside,
+// still has a whole pair and never sees a file it mapped being rewritten.
+func saveIndex(directory string, index *noteIndex, fresh map[string]freshFile) (*noteIndex, error) {
+	filename := indexFile(directory)
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := emptyIndex()
+	for path := range index.Files {
+		saved.Paths = append(saved.Paths, path)
+	}
+	sort.Strings(saved.Paths)
+
+	dataFile, generation, err := createIndexData(directory, index.Generation+1)
+	if err != nil {
+		return nil, err
+	}
+	saved.Generation = generation
+	out := bufio.NewWriter(dataFile)
+	var offset int64
+	write := func(content []byte) span {
+		out.Write(content)
+		written := span{Offset: offset, Length: int64(len(content))}
+		offset += int64(len(content))
+		return written
+	}
+
+	postings := make(map[uint64][]int32)
+	for id, path := range saved.Paths {
+		indexed := index.Files[path]
+		var packed []byte
+		var trigrams []uint64
+		if file, ok := fresh[path]; ok {
+			packed, trigrams = file.packed, file.trigrams
+		} else if !indexed.Binary {
+			packed = indexed.Packed.of(index.data)
+			trigrams = decodeTrigrams(indexed.Trigrams.of(index.data))
+		}
+		indexed.Packed = write(packed)
+		indexed.Trigrams = write(encodeUint64s(trigrams))
+		saved.Files[path] = indexed
+		for _, trigram := range trigrams {
+			postings[trigram] = append(postings[trigram], int32(id))
+		}
+	}
+
+	keys := make([]uint64, 0, len(postings))
+	for trigram := range postings {
+		keys = append(keys, trigram)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	var starts, ids []uint32
+	for _, trigram := range keys {
+		starts = append(starts, uint32(len(ids)))
+		for _, id := range postings[trigram] {
+			ids = append(ids, uint32(id))
+		}
+	}
+	starts = append(starts, uint32(len(ids)))
+	saved.PostingKeys = write(encodeUint64s(keys))
+	saved.PostingStarts = write(encodeUint32s(starts))
+	saved.PostingIds = write(encodeUint32s(ids))
+
+	err = out.Flush()
+	if closeErr := dataFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = saveIndexList(directory, saved)
+	}
+	if err != nil {
+		os.Remove(indexDataFile(directory, saved.Generation))
+		return nil, err
+	}
+	removeIndexData(directory, index.Generation)
+
+	saved.data, err = mapFile(indexDataFile(directory, saved.Generation))
+	return saved, err
+}
+
+// a new data file for the index, at the first generation from generation
+// on that no other save has taken
+func createIndexData(directory string, generation int64) (*os.File, int64, error) {
+	for {
+		file, err := os.OpenFile(indexDataFile(directory, generation), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if !os.IsExist(err) {
+			return file, generation, err
+		}
+		generation++
+	}
+}
+
+// remove the data files of the generations up to the one a save replaced,
+// including any a save running alongside left behind. A search that has
+// one mapped keeps reading it until it's done.
+func removeIndexData(directory string, replaced int64) {
+	for generation := replaced; generation > 0; generation-- {
+		err := os.Remove(indexDataFile(directory, generation))
+		if os.IsNotExist(err) && generation < replaced {
+			return
+		}
+	}
+}
+
+// write the list of files, which points to its data file, in place. Each
+// save writes its own temporary file, so two at once can't mix theirs up.
+func saveIndexList(directory string, index *noteIndex) error {
+	filename := indexFile(directory)
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(file).Encode(index)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func encodeUint64s(values []uint64) []byte {
+	encoded := make([]byte, len(values)*8)
+	for index, value := range values {
+		binary.LittleEndian.PutUint64(encoded[index*8:], value)
+	}
+	return encoded
+}
+
+func encodeUint32s(values []uint32) []byte {
+	encoded := make([]byte, len(values)*4)
+	for index, value := range values {
+		binary.LittleEndian.PutUint32(encoded[index*4:], value)
+	}
+	return encoded
+}
+
+func decodeTrigrams(encoded []byte) []uint64 {
+	trigrams := make([]uint64, len(encoded)/8)
+	for index := range trigrams {
+		trigrams[index] = binary.LittleEndian.Uint64(encoded[index*8:])
+	}
+	return trigrams
+}
+
+// UpdateIndex brings the index of the vault in directory up to date, as the
+// first search with the index backend would, so that search doesn't have to
+func UpdateIndex(directory string, config Config) error {
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return exitError(ExitVault, "no such directory %s", directory)
+	}
+	config.directory = directory
+	_, err := updateIndex(config)
+	return err
+}
@@ -0,0 +1,120 @@
+package osearch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestIndexUnsaved(t *testing.T) {
+	directory := testVault(t, map[string]string{"Fruit.md": "apples and pears\n"})
+	// a data folder that can't be created, so the index can't be saved
+	blocked := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(blocked, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("alfred_workflow_data", filepath.Join(blocked, "data"))
+
+	config := Config{directory: directory}
+	backend := &indexBackend{}
+	matches, err := backend.GrepContent("apples", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Path != "Fruit.md" {
+		t.Errorf("found %+v in an index that couldn't be saved, want Fruit.md", matches)
+	}
+	if err := UpdateIndex(directory, config); err == nil {
+		t.Errorf("updating an index that couldn't be saved didn't fail")
+	}
+}
+
+func TestIndexGenerations(t *testing.T) {
+	directory := testVault(t, map[string]string{"Fruit.md": "apples and pears\n"})
+	config := Config{directory: directory}
+	first, err := updateIndex(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another save has taken the next generation in the meantime
+	taken, generation, err := createIndexData(directory, first.Generation+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken.Close()
+	if generation != first.Generation+1 {
+		t.Fatalf("took generation %d, want %d", generation, first.Generation+1)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(directory, "Fruit.md"), []byte("plums\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	second, err := updateIndex(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Generation != generation+1 {
+		t.Errorf("saved generation %d, want %d past the one taken", second.Generation, generation+1)
+	}
+	if _, err := os.Stat(indexDataFile(directory, first.Generation)); !os.IsNotExist(err) {
+		t.Errorf("the replaced generation's data is still there")
+	}
+
+	backend := &indexBackend{index: loadIndex(directory), texts: make(map[string]string)}
+	if text := backend.text("Fruit.md"); text != "plums\n" {
+		t.Errorf("loaded %q, want the second save's text", text)
+	}
+}
+
+// the service and searches save the index at the same time as others read
+// it, and none of them may see another's half-written files
+func TestIndexConcurrentSaves(t *testing.T) {
+	directory := testVault(t, map[string]string{"Fruit.md": "apples and pears\n", "Changing.md": "0\n"})
+	config := Config{directory: directory}
+	if _, err := updateIndex(config); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan string, 100)
+	done := make(chan bool)
+	for writer := 0; writer < 2; writer++ {
+		go func(writer int) {
+			for round := 0; round < 20; round++ {
+				text := []byte(strconv.Itoa(writer*100+round) + "\n")
+				if err := ioutil.WriteFile(filepath.Join(directory, "Changing.md"), text, 0600); err != nil {
+					errs <- err.Error()
+				}
+				// a save losing the race to another is tried again next time
+				index, _ := updateIndex(config)
+				backend := &indexBackend{index: index, texts: make(map[string]string)}
+				if text := backend.text("Fruit.md"); text != "apples and pears\n" {
+					errs <- "a save read " + strconv.Quote(text)
+				}
+			}
+			done <- true
+		}(writer)
+	}
+	go func() {
+		for round := 0; round < 50; round++ {
+			index := loadIndex(directory)
+			if _, ok := index.Files["Fruit.md"]; !ok {
+				continue
+			}
+			backend := &indexBackend{index: index, texts: make(map[string]string)}
+			if text := backend.text("Fruit.md"); text != "apples and pears\n" {
+				errs <- "a search read " + strconv.Quote(text)
+			}
+		}
+		done <- true
+	}()
+	for running := 3; running > 0; running-- {
+		<-done
+	}
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,83 @@
+package osearch
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// the notes a note links to, as written: [[wikilinks]], ![[embeds]] and
+// markdown links to other notes, without any #heading or |alias
+func noteLinks(content string) []string {
+	var targets []string
+	for _, match := range wikilinkPattern.FindAllStringSubmatch(content, -1) {
+		targets = append(targets, match[1])
+	}
+	for _, match := range linkPattern.FindAllStringSubmatch(content, -1) {
+		if strings.Contains(match[2], "://") {
+			continue
+		}
+		target, err := url.PathUnescape(match[2])
+		if err != nil {
+			target = match[2]
+		}
+		targets = append(targets, target)
+	}
+
+	var links []string
+	for _, target := range targets {
+		target = strings.TrimSpace(strings.SplitN(target, "#", 2)[0])
+		if len(target) > 0 {
+			links = append(links, target)
+		}
+	}
+	return links
+}
+
+// linkResolver finds the file a link points at the way Obsidian does:
+// by its path in the vault if that's what the link has, otherwise by its
+// name, preferring a note next to the one linking when names clash
+type linkResolver struct {
+	byPath map[string]string
+	byName map[string][]string
+}
+
+func newLinkResolver(files []string) linkResolver {
+	resolver := linkResolver{byPath: make(map[string]string), byName: make(map[string][]string)}
+	for _, file := range files {
+		key := strings.ToLower(path.Clean(file))
+		resolver.byPath[key] = file
+		name := path.Base(key)
+		resolver.byName[name] = append(resolver.byName[name], file)
+	}
+	for _, candidates := range resolver.byName {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return len(candidates[i]) < len(candidates[j])
+		})
+	}
+	return resolver
+}
+
+func (resolver linkResolver) resolve(target string, from string) (string, bool) {
+	key := strings.ToLower(path.Clean(strings.TrimPrefix(target, "/")))
+	if path.Ext(key) == "" {
+		key += ".md"
+	}
+	if file, ok := resolver.byPath[key]; ok {
+		return file, true
+	}
+	if file, ok := resolver.byPath[strings.ToLower(path.Join(path.Dir(from), key))]; ok {
+		return file, true
+	}
+	candidates := resolver.byName[path.Base(key)]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	for _, candidate := range candidates {
+		if path.Dir(candidate) == path.Dir(from) {
+			return candidate, true
+		}
+	}
+	return candidates[0], true
+}
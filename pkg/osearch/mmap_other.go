@@ -0,0 +1,11 @@
+//go:build !darwin && !linux && !freebsd && !netbsd && !openbsd
+// +build !darwin,!linux,!freebsd,!netbsd,!openbsd
+
+package osearch
+
+import "io/ioutil"
+
+// mapFile reads the whole file where there's no mmap to lean on
+func mapFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
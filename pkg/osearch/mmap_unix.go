@@ -0,0 +1,27 @@
+//go:build darwin || linux || freebsd || netbsd || openbsd
+// +build darwin linux freebsd netbsd openbsd
+
+package osearch
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps a file into memory read-only, leaving the OS to read in the
+// pages that get used and to keep them cached between searches
+func mapFile(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return []byte{}, nil
+	}
+	return syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
@@ -0,0 +1,86 @@
+package osearch
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+// ParseTimeBound reads a point in time either as a date (2024-01-01, or
+// with a time as 2024-01-01T15:04) or as how long ago it was (90m, 12h,
+// 7d, 2w, 1y)
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if len(value) > 1 {
+		unit, ok := durationUnits[strings.ToLower(value[len(value)-1:])]
+		count, err := strconv.Atoi(value[:len(value)-1])
+		if ok && err == nil && count >= 0 {
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s is neither a date like 2024-01-31 nor an age like 7d", value)
+}
+
+// the results last modified between since and before; a zero time leaves
+// that end open
+func modifiedBetween(results []AlfredResult, since time.Time, before time.Time) []AlfredResult {
+	if since.IsZero() && before.IsZero() {
+		return results
+	}
+	var kept []AlfredResult
+	for _, result := range results {
+		info, err := os.Stat(result.Variables["fullpath"])
+		if err != nil {
+			continue
+		}
+		modified := info.ModTime()
+		if (since.IsZero() || !modified.Before(since)) && (before.IsZero() || modified.Before(before)) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+func plural(count int, unit string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
+
+// how long ago t was, the way a person would say it
+func humanizeAge(t time.Time, now time.Time) string {
+	age := now.Sub(t)
+	days := int(age.Hours() / 24)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return plural(int(age.Minutes()), "minute") + " ago"
+	case age < 24*time.Hour:
+		return plural(int(age.Hours()), "hour") + " ago"
+	case days == 1:
+		return "yesterday"
+	case days < 14:
+		return plural(days, "day") + " ago"
+	case days < 60:
+		return plural(days/7, "week") + " ago"
+	case days < 365:
+		return plural(days/30, "month") + " ago"
+	}
+	return plural(days/365, "year") + " ago"
+}
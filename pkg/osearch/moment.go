@@ -0,0 +1,132 @@
+package osearch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the moment.js format tokens Obsidian's date settings use, longest first so
+// that MMMM wins over MM
+var momentTokens = []string{
+	"YYYY", "GGGG", "MMMM", "DDDD", "dddd",
+	"MMM", "DDD", "ddd",
+	"YY", "MM", "Do", "DD", "dd", "WW", "HH", "hh", "mm", "ss",
+	"Q", "M", "D", "d", "E", "W", "H", "h", "m", "s", "A", "a",
+}
+
+// formatMoment writes t in a moment.js format such as "YYYY-MM-DD" or
+// "dddd, MMMM Do YYYY". Text in [brackets] is kept as it is, as are letters
+// that aren't tokens.
+func formatMoment(t time.Time, format string) string {
+	var out strings.Builder
+	for len(format) > 0 {
+		if format[0] == '[' {
+			end := strings.IndexByte(format, ']')
+			if end < 0 {
+				out.WriteString(format[1:])
+				break
+			}
+			out.WriteString(format[1:end])
+			format = format[end+1:]
+			continue
+		}
+		matched := false
+		for _, token := range momentTokens {
+			if strings.HasPrefix(format, token) {
+				out.WriteString(momentToken(t, token))
+				format = format[len(token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out.WriteByte(format[0])
+			format = format[1:]
+		}
+	}
+	return out.String()
+}
+
+func momentToken(t time.Time, token string) string {
+	isoYear, isoWeek := t.ISOWeek()
+	switch token {
+	case "YYYY":
+		return fmt.Sprintf("%04d", t.Year())
+	case "YY":
+		return fmt.Sprintf("%02d", t.Year()%100)
+	case "GGGG":
+		return fmt.Sprintf("%04d", isoYear)
+	case "Q":
+		return fmt.Sprint((int(t.Month())-1)/3 + 1)
+	case "MMMM":
+		return t.Month().String()
+	case "MMM":
+		return t.Month().String()[:3]
+	case "MM":
+		return fmt.Sprintf("%02d", int(t.Month()))
+	case "M":
+		return fmt.Sprint(int(t.Month()))
+	case "DDDD":
+		return fmt.Sprintf("%03d", t.YearDay())
+	case "DDD":
+		return fmt.Sprint(t.YearDay())
+	case "DD":
+		return fmt.Sprintf("%02d", t.Day())
+	case "Do":
+		return ordinal(t.Day())
+	case "D":
+		return fmt.Sprint(t.Day())
+	case "dddd":
+		return t.Weekday().String()
+	case "ddd":
+		return t.Weekday().String()[:3]
+	case "dd":
+		return t.Weekday().String()[:2]
+	case "d":
+		return fmt.Sprint(int(t.Weekday()))
+	case "E":
+		return fmt.Sprint((int(t.Weekday())+6)%7 + 1)
+	case "WW":
+		return fmt.Sprintf("%02d", isoWeek)
+	case "W":
+		return fmt.Sprint(isoWeek)
+	case "HH":
+		return fmt.Sprintf("%02d", t.Hour())
+	case "H":
+		return fmt.Sprint(t.Hour())
+	case "hh":
+		return fmt.Sprintf("%02d", (t.Hour()+11)%12+1)
+	case "h":
+		return fmt.Sprint((t.Hour()+11)%12 + 1)
+	case "mm":
+		return fmt.Sprintf("%02d", t.Minute())
+	case "m":
+		return fmt.Sprint(t.Minute())
+	case "ss":
+		return fmt.Sprintf("%02d", t.Second())
+	case "s":
+		return fmt.Sprint(t.Second())
+	case "A":
+		return t.Format("PM")
+	case "a":
+		return t.Format("pm")
+	}
+	return token
+}
+
+// 1st, 2nd, 3rd, 4th, ..., 11th, 12th, 13th, ..., 21st
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,239 @@
+package osearch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// nativeBackend walks and reads the vault itself. Like fd and rg it skips
+// hidden files and folders unless asked not to, but it doesn't read
+// .gitignore. It keeps a Bloom filter of each file's trigrams so that
+// content searches skip the files that can't match.
+type nativeBackend struct {
+	filters *bloomCache
+}
+
+func (*nativeBackend) FindFiles(pattern string, config Config) ([]string, error) {
+	return namesAndPlaceholders(walkVault(config), pattern, config)
+}
+
+func (backend *nativeBackend) FilesContaining(pattern string, config Config) (map[string]bool, error) {
+	re, err := compileBackendPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	backend.eachText(pattern, config, func(file string, content []byte) {
+		if hasMatch(string(content), re, config) {
+			files[file] = true
+		}
+	})
+	return files, nil
+}
+
+func (backend *nativeBackend) GrepContent(pattern string, config Config) ([]LineMatch, error) {
+	re, err := compileBackendPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var matches []LineMatch
+	backend.eachText(pattern, config, func(file string, content []byte) {
+		matches = append(matches, grepText(file, string(content), re, config)...)
+	})
+	return matches, nil
+}
+
+// call do with each text file under the folders config searches that may
+// match pattern, then save any filters that changed
+func (backend *nativeBackend) eachText(pattern string, config Config, do func(file string, content []byte)) {
+	if backend.filters == nil {
+		backend.filters = loadBloomCache(config.directory)
+	}
+	trigrams := patternTrigrams(pattern)
+	seen := make(map[string]bool)
+	for _, file := range walkVault(config) {
+		seen[file] = true
+		if content, ok := backend.filters.read(file, trigrams); ok {
+			do(file, content)
+		}
+	}
+	if len(config.Folders) == 0 && config.MaxDepth <= 0 {
+		backend.filters.keepOnly(seen)
+	}
+	backend.filters.save()
+}
+
+// every file under the folders config searches, leaving out hidden ones
+func walkVault(config Config) []string {
+	roots := config.Folders
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	var files []string
+	visited := make(map[string]bool)
+	for _, root := range roots {
+		walkFolder(root, config, visited, &files)
+	}
+	return files
+}
+
+func walkFolder(folder string, config Config, visited map[string]bool, files *[]string) {
+	// a link back up the tree would have us going round forever
+	if config.Follow {
+		real, err := filepath.EvalSymlinks(config.vaultPath(folder))
+		if err == nil {
+			real, err = filepath.Abs(real)
+		}
+		if err != nil || visited[real] {
+			return
+		}
+		visited[real] = true
+	}
+	entries, err := ioutil.ReadDir(config.vaultPath(folder))
+	if err != nil {
+		return
+	}
+	for _, info := range entries {
+		hidden := strings.HasPrefix(info.Name(), ".") && (!config.Hidden || excludedHidden(info.Name(), config))
+		if hidden && !(config.placeholders && info.Mode().IsRegular() && isPlaceholder(info.Name())) {
+			continue
+		}
+		path := filepath.Join(folder, info.Name())
+		if isExcluded(path, config.Exclude) {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !config.Follow {
+				continue
+			}
+			info, err = os.Stat(config.vaultPath(path))
+			if err != nil {
+				continue
+			}
+		}
+		if info.IsDir() {
+			// nothing inside a folder this deep would be shallow enough
+			if config.MaxDepth <= 0 || pathDepth(path) < config.MaxDepth {
+				walkFolder(path, config, visited, files)
+			}
+		} else if info.Mode().IsRegular() && withinDepth(path, config) {
+			*files = append(*files, path)
+		}
+	}
+}
+
+func excludedHidden(name string, config Config) bool {
+	for _, excluded := range config.HiddenExclude {
+		if name == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// whether anything along path is hidden
+func isHiddenPath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func namesMatching(files []string, pattern string) ([]string, error) {
+	if len(pattern) == 0 {
+		return files, nil
+	}
+	re, err := compileBackendPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, file := range files {
+		if re.MatchString(filepath.Base(file)) {
+			matches = append(matches, file)
+		}
+	}
+	return matches, nil
+}
+
+func compileBackendPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, exitError(ExitUsage, "could not search for %s: %s", pattern, err)
+	}
+	return re, nil
+}
+
+// a file's contents as UTF-8, unless it looks binary the way rg decides: a
+// NUL byte near the start
+func readText(file string) ([]byte, bool) {
+	content, err := readNote(file)
+	if err != nil {
+		return nil, false
+	}
+	return content, !isBinary(content)
+}
+
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// whether re matches text. rg matches a line at a time unless it's
+// searching multiline, so patterns mean the same here.
+func hasMatch(text string, re *regexp.Regexp, config Config) bool {
+	if config.Multiline {
+		return re.MatchString(text)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// the lines of text that re matches, numbered from 1 as rg numbers them.
+// A multiline match comes back as one LineMatch holding all the lines it
+// spans, numbered by the first.
+func grepText(file string, text string, re *regexp.Regexp, config Config) []LineMatch {
+	var matches []LineMatch
+	if config.Multiline {
+		lastLine := -1
+		for _, span := range re.FindAllStringIndex(text, -1) {
+			start := strings.LastIndex(text[:span[0]], "\n") + 1
+			if start <= lastLine {
+				// rg reports each line once, with the first match on it
+				continue
+			}
+			end := len(text)
+			if newline := strings.Index(text[span[1]:], "\n"); newline >= 0 {
+				end = span[1] + newline
+			}
+			matches = append(matches, LineMatch{
+				Path:   file,
+				Number: strings.Count(text[:start], "\n") + 1,
+				Text:   text[start:end],
+				Start:  span[0] - start,
+				End:    span[1] - start,
+			})
+			lastLine = end
+		}
+		return matches
+	}
+	for index, line := range strings.Split(text, "\n") {
+		if span := re.FindStringIndex(line); span != nil {
+			matches = append(matches, LineMatch{Path: file, Number: index + 1, Text: line, Start: span[0], End: span[1]})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,74 @@
+package osearch
+
+import (
+	"strings"
+)
+
+// normalize a word for comparison the way the search would
+func comparable(word string, caseSensitive bool) string {
+	word = foldDiacritics(word)
+	if !caseSensitive {
+		word = strings.ToLower(word)
+	}
+	return word
+}
+
+// the word positions at which phrase starts in words
+func phrasePositions(words []string, phrase []string) []int {
+	var positions []int
+	if len(phrase) == 0 {
+		return positions
+	}
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		found := true
+		for offset, word := range phrase {
+			if words[start+offset] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			positions = append(positions, start)
+		}
+	}
+	return positions
+}
+
+// withinWords reports whether a and b (words or phrases) occur in text with
+// at most distance words between them, in either order
+func withinWords(text string, a string, b string, distance int, caseSensitive bool) bool {
+	normalize := func(words []string) []string {
+		for index, word := range words {
+			words[index] = comparable(word, caseSensitive)
+		}
+		return words
+	}
+	words := normalize(tokenize(text))
+	aWords := normalize(tokenize(a))
+	bWords := normalize(tokenize(b))
+
+	bPositions := phrasePositions(words, bWords)
+	for _, aStart := range phrasePositions(words, aWords) {
+		for _, bStart := range bPositions {
+			var gap int
+			if aStart < bStart {
+				gap = bStart - (aStart + len(aWords))
+			} else {
+				gap = aStart - (bStart + len(bWords))
+			}
+			if gap <= distance && gap >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fileHasNear(file string, a string, b string, distance int, config Config) bool {
+	content, err := readNote(config.vaultPath(file))
+	if err != nil {
+		return false
+	}
+	caseSensitive := isCaseSensitive(a, config.Case) || isCaseSensitive(b, config.Case)
+	return withinWords(string(content), a, b, distance, caseSensitive)
+}
@@ -0,0 +1,48 @@
+package osearch
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// nestedVaultFinder remembers which folders of a vault are vaults of their
+// own, having an .obsidian folder, so each is only looked at once
+type nestedVaultFinder struct {
+	directory string
+	roots     map[string]bool
+}
+
+func (finder nestedVaultFinder) isVault(folder string) bool {
+	isVault, ok := finder.roots[folder]
+	if !ok {
+		info, err := os.Stat(filepath.Join(finder.directory, folder, ".obsidian"))
+		isVault = err == nil && info.IsDir()
+		finder.roots[folder] = isVault
+	}
+	return isVault
+}
+
+// whether the vault-relative filename is inside another vault nested in
+// this one
+func (finder nestedVaultFinder) nested(filename string) bool {
+	for folder := filepath.Dir(filepath.Clean(filename)); folder != "." && folder != string(filepath.Separator); folder = filepath.Dir(folder) {
+		if finder.isVault(folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// leave out notes that belong to a vault nested inside this one: Obsidian
+// would open them in this vault, not their own
+func withoutNestedVaults(results []AlfredResult, directory string) []AlfredResult {
+	finder := nestedVaultFinder{directory: directory, roots: make(map[string]bool)}
+	var kept []AlfredResult
+	for _, result := range results {
+		path, ok := result.Variables["path"]
+		if !ok || !finder.nested(path) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,144 @@
+package osearch
+
+// letters with a mark and what they're made of, by the combining mark: each
+// string runs base letter, composed letter, base letter, composed letter...
+// These are the Latin, Greek, Cyrillic and kana letters Unicode composes,
+// which covers what macOS decomposes in file names; Hangul is worked out
+// instead.
+var composingMarks = map[rune]string{
+	0x0300: "AÀEÈIÌOÒUÙaàeèiìoòuùÜǛüǜNǸnǹЕЀИЍеѐиѝĒḔēḕŌṐōṑWẀwẁÂẦâầĂẰăằÊỀêềÔỒôồƠỜơờƯỪưừYỲyỳἀἂἁἃἈἊἉἋἐἒἑἓἘἚἙἛἠἢἡἣἨἪἩἫἰἲἱἳἸἺἹἻὀὂὁὃὈὊὉὋὐὒὑὓὙὛὠὢὡὣὨὪὩὫαὰεὲηὴιὶοὸυὺωὼΑᾺΕῈΗῊ᾿῍ϊῒΙῚ῾῝ϋῢΥῪ¨῭ΟῸΩῺ",
+	0x0301: "AÁEÉIÍOÓUÚYÝaáeéiíoóuúyýCĆcćLĹlĺNŃnńRŔrŕSŚsśZŹzźÜǗüǘGǴgǵÅǺåǻÆǼæǽØǾøǿ¨΅ΑΆΕΈΗΉΙΊΟΌΥΎΩΏϊΐαάεέηήιίϋΰοόυύωώϒϓГЃКЌгѓкќÇḈçḉĒḖēḗÏḮïḯKḰkḱMḾmḿÕṌõṍŌṒōṓPṔpṕŨṸũṹWẂwẃÂẤâấĂẮăắÊẾêếÔỐôốƠỚơớƯỨưứἀἄἁἅἈἌἉἍἐἔἑἕἘἜἙἝἠἤἡἥἨἬἩἭἰἴἱἵἸἼἹἽὀὄὁὅὈὌὉὍὐὔὑὕὙὝὠὤὡὥὨὬὩὭ᾿῎῾῞",
+	0x0302: "AÂEÊIÎOÔUÛaâeêiîoôuûCĈcĉGĜgĝHĤhĥJĴjĵSŜsŝWŴwŵYŶyŷZẐzẑẠẬạậẸỆẹệỌỘọộ",
+	0x0303: "AÃNÑOÕaãnñoõIĨiĩUŨuũVṼvṽÂẪâẫĂẴăẵEẼeẽÊỄêễÔỖôỗƠỠơỡƯỮưữYỸyỹ",
+	0x0304: "AĀaāEĒeēIĪiīOŌoōUŪuūÜǕüǖÄǞäǟȦǠȧǡÆǢæǣǪǬǫǭÖȪöȫÕȬõȭȮȰȯȱYȲyȳИӢиӣУӮуӯGḠgḡḶḸḷḹṚṜṛṝαᾱΑᾹιῑΙῙυῡΥῩ",
+	0x0306: "AĂaăEĔeĕGĞgğIĬiĭOŎoŏUŬuŭУЎИЙийуўЖӁжӂАӐаӑЕӖеӗȨḜȩḝẠẶạặαᾰΑᾸιῐΙῘυῠΥῨ",
+	0x0307: "CĊcċEĖeėGĠgġIİZŻzżAȦaȧOȮoȯBḂbḃDḊdḋFḞfḟHḢhḣMṀmṁNṄnṅPṖpṗRṘrṙSṠsṡŚṤśṥŠṦšṧṢṨṣṩTṪtṫWẆwẇXẊxẋYẎyẏſẛ",
+	0x0308: "AÄEËIÏOÖUÜaäeëiïoöuüyÿYŸΙΪΥΫιϊυϋϒϔЕЁІЇеёіїАӒаӓӘӚәӛЖӜжӝЗӞзӟИӤиӥОӦоӧӨӪөӫЭӬэӭУӰуӱЧӴчӵЫӸыӹHḦhḧÕṎõṏŪṺūṻWẄwẅXẌxẍtẗ",
+	0x0309: "AẢaảÂẨâẩĂẲăẳEẺeẻÊỂêểIỈiỉOỎoỏÔỔôổƠỞơởUỦuủƯỬưửYỶyỷ",
+	0x030a: "AÅaåUŮuůwẘyẙ",
+	0x030b: "OŐoőUŰuűУӲуӳ",
+	0x030c: "CČcčDĎdďEĚeěLĽlľNŇnňRŘrřSŠsšTŤtťZŽzžAǍaǎIǏiǐOǑoǒUǓuǔÜǙüǚGǦgǧKǨkǩƷǮʒǯjǰHȞhȟ",
+	0x030f: "AȀaȁEȄeȅIȈiȉOȌoȍRȐrȑUȔuȕѴѶѵѷ",
+	0x0311: "AȂaȃEȆeȇIȊiȋOȎoȏRȒrȓUȖuȗ",
+	0x0313: "αἀΑἈεἐΕἘηἠΗἨιἰΙἸοὀΟὈυὐωὠΩὨρῤ",
+	0x0314: "αἁΑἉεἑΕἙηἡΗἩιἱΙἹοὁΟὉυὑΥὙωὡΩὩρῥΡῬ",
+	0x031b: "OƠoơUƯuư",
+	0x0323: "BḄbḅDḌdḍHḤhḥKḲkḳLḶlḷMṂmṃNṆnṇRṚrṛSṢsṣTṬtṭVṾvṿWẈwẉZẒzẓAẠaạEẸeẹIỊiịOỌoọƠỢơợUỤuụƯỰưựYỴyỵ",
+	0x0324: "UṲuṳ",
+	0x0325: "AḀaḁ",
+	0x0326: "SȘsșTȚtț",
+	0x0327: "CÇcçGĢgģKĶkķLĻlļNŅnņRŖrŗSŞsşTŢtţEȨeȩDḐdḑHḨhḩ",
+	0x0328: "AĄaąEĘeęIĮiįUŲuųOǪoǫ",
+	0x032d: "DḒdḓEḘeḙLḼlḽNṊnṋTṰtṱUṶuṷ",
+	0x032e: "HḪhḫ",
+	0x0330: "EḚeḛIḬiḭUṴuṵ",
+	0x0331: "BḆbḇDḎdḏKḴkḵLḺlḻNṈnṉRṞrṟTṮtṯZẔzẕhẖ",
+	0x0342: "ἀἆἁἇἈἎἉἏἠἦἡἧἨἮἩἯἰἶἱἷἸἾἹἿὐὖὑὗὙὟὠὦὡὧὨὮὩὯαᾶ¨῁ηῆ᾿῏ιῖϊῗ῾῟υῦϋῧωῶ",
+	0x0345: "ἀᾀἁᾁἂᾂἃᾃἄᾄἅᾅἆᾆἇᾇἈᾈἉᾉἊᾊἋᾋἌᾌἍᾍἎᾎἏᾏἠᾐἡᾑἢᾒἣᾓἤᾔἥᾕἦᾖἧᾗἨᾘἩᾙἪᾚἫᾛἬᾜἭᾝἮᾞἯᾟὠᾠὡᾡὢᾢὣᾣὤᾤὥᾥὦᾦὧᾧὨᾨὩᾩὪᾪὫᾫὬᾬὭᾭὮᾮὯᾯὰᾲαᾳάᾴᾶᾷΑᾼὴῂηῃήῄῆῇΗῌὼῲωῳώῴῶῷΩῼ",
+	0x3099: "かがきぎくぐけげこごさざしじすずせぜそぞただちぢつづてでとどはばひびふぶへべほぼうゔゝゞカガキギクグケゲコゴサザシジスズセゼソゾタダチヂツヅテデトドハバヒビフブヘベホボウヴワヷヰヸヱヹヲヺヽヾ",
+	0x309a: "はぱひぴふぷへぺほぽハパヒピフプヘペホポ",
+}
+
+// the Hangul syllable block, whose syllables are composed arithmetically
+// from their jamo
+const (
+	hangulBase      = 0xac00
+	hangulLeadBase  = 0x1100
+	hangulVowelBase = 0x1161
+	hangulTailBase  = 0x11a7
+	hangulVowels    = 21
+	hangulTails     = 28
+	hangulSyllables = 19 * hangulVowels * hangulTails
+)
+
+var (
+	composedLetters   = make(map[[2]rune]rune)
+	decomposedLetters = make(map[rune][2]rune)
+)
+
+func init() {
+	for mark, letters := range composingMarks {
+		runes := []rune(letters)
+		for index := 0; index+1 < len(runes); index += 2 {
+			composedLetters[[2]rune{runes[index], mark}] = runes[index+1]
+			decomposedLetters[runes[index+1]] = [2]rune{runes[index], mark}
+		}
+	}
+}
+
+// toNFC composes letters macOS keeps decomposed in file names, like "u"
+// followed by a combining diaeresis, into the single letter ("ü") they'd
+// be typed as, so both compare equal
+func toNFC(s string) string {
+	if isNFC(s) {
+		return s
+	}
+	runes := []rune(s)
+	composed := runes[:0]
+	// where the letter marks can still join is, or -1
+	starter := -1
+	for _, r := range runes {
+		if starter >= 0 && starter == len(composed)-1 {
+			if letter, ok := composeRunes(composed[starter], r); ok {
+				composed[starter] = letter
+				continue
+			}
+		}
+		if _, ok := composingMarks[r]; !ok && !isHangulFollower(r) {
+			starter = len(composed)
+		}
+		composed = append(composed, r)
+	}
+	return string(composed)
+}
+
+// whether s has nothing to compose, as almost everything doesn't
+func isNFC(s string) bool {
+	for _, r := range s {
+		if _, ok := composingMarks[r]; ok || isHangulJamo(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func composeRunes(letter rune, mark rune) (rune, bool) {
+	if composed, ok := composedLetters[[2]rune{letter, mark}]; ok {
+		return composed, true
+	}
+	// a leading consonant and a vowel make a syllable, which can take a
+	// trailing consonant
+	if letter >= hangulLeadBase && letter < hangulLeadBase+19 && mark >= hangulVowelBase && mark < hangulVowelBase+hangulVowels {
+		return hangulBase + ((letter-hangulLeadBase)*hangulVowels+mark-hangulVowelBase)*hangulTails, true
+	}
+	if letter >= hangulBase && letter < hangulBase+hangulSyllables && (letter-hangulBase)%hangulTails == 0 && mark > hangulTailBase && mark < hangulTailBase+hangulTails {
+		return letter + mark - hangulTailBase, true
+	}
+	return 0, false
+}
+
+func isHangulJamo(r rune) bool {
+	return r >= hangulLeadBase && r < 0x1200
+}
+
+// a vowel or trailing consonant, which joins the syllable before it
+func isHangulFollower(r rune) bool {
+	return r >= hangulVowelBase && r < 0x1200
+}
+
+// decomposeLetter spells a letter the way macOS stores it in file names,
+// or returns it as it is when there's nothing to decompose
+func decomposeLetter(r rune) string {
+	if parts, ok := decomposedLetters[r]; ok {
+		return decomposeLetter(parts[0]) + string(parts[1])
+	}
+	if r >= hangulBase && r < hangulBase+hangulSyllables {
+		index := r - hangulBase
+		jamo := []rune{hangulLeadBase + index/(hangulVowels*hangulTails), hangulVowelBase + index%(hangulVowels*hangulTails)/hangulTails}
+		if tail := index % hangulTails; tail > 0 {
+			jamo = append(jamo, hangulTailBase+tail)
+		}
+		return string(jamo)
+	}
+	return string(r)
+}
@@ -0,0 +1,711 @@
+// Package osearch searches Obsidian vaults: by file name, fuzzily, or
+// through their contents with rg, and writes the results for Alfred and
+// other launchers. The osearch command in cmd/osearch is a thin wrapper
+// around Search and WriteResults.
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ObsidianVault struct {
+	Path string `json:"path"`
+	Ts   int    `json:"ts"`
+	Open bool   `json:"open"`
+}
+
+type ObsidianConfig struct {
+	Vaults map[string]ObsidianVault `json:"vaults"`
+}
+
+type AlfredResults struct {
+	Cache *AlfredCache `json:"cache,omitempty"`
+	Rerun float64      `json:"rerun,omitempty"`
+	// keep Alfred from reordering results we've already sorted
+	SkipKnowledge bool           `json:"skipknowledge,omitempty"`
+	Items         []AlfredResult `json:"items"`
+}
+
+// ask Alfred to reuse these results instead of running us again
+type AlfredCache struct {
+	Seconds     int  `json:"seconds"`
+	LooseReload bool `json:"loosereload,omitempty"`
+}
+
+// how long Alfred may cache the output of modes that are slow to produce
+const ListCacheSeconds = 300
+
+func cacheFor(seconds int) *AlfredCache {
+	if seconds <= 0 {
+		return nil
+	}
+	// Alfred only accepts between five seconds and a day
+	if seconds < 5 {
+		seconds = 5
+	} else if seconds > 86400 {
+		seconds = 86400
+	}
+	return &AlfredCache{Seconds: seconds, LooseReload: true}
+}
+
+// Alfred reruns the script filter every 0.1 to 5 seconds, or not at all
+func rerunAfter(seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	if seconds < 0.1 {
+		return 0.1
+	} else if seconds > 5 {
+		return 5
+	}
+	return seconds
+}
+
+type AlfredResult struct {
+	UID          string               `json:"uid,omitempty"`
+	Type         string               `json:"type"`
+	Valid        *bool                `json:"valid,omitempty"`
+	Title        string               `json:"title"`
+	Subtitle     string               `json:"subtitle"`
+	Arg          string               `json:"arg"`
+	Autocomplete string               `json:"autocomplete,omitempty"`
+	QuicklookUrl string               `json:"quicklookurl,omitempty"`
+	Match        string               `json:"match,omitempty"`
+	Mods         map[string]AlfredMod `json:"mods,omitempty"`
+	Text         *AlfredText          `json:"text,omitempty"`
+	Icon         *AlfredIcon          `json:"icon,omitempty"`
+	Variables    map[string]string    `json:"variables,omitempty"`
+	Action       *AlfredAction        `json:"action,omitempty"`
+}
+
+// what Alfred's Universal Actions operate on
+type AlfredAction struct {
+	Text string `json:"text,omitempty"`
+	Url  string `json:"url,omitempty"`
+	File string `json:"file,omitempty"`
+}
+
+// what ⌘C copies and ⌘L shows in Large Type
+type AlfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+// an alternative action on a result, chosen by holding a modifier key. The
+// action variable tells the workflow what to do with the arg.
+type AlfredMod struct {
+	Valid     bool              `json:"valid"`
+	Arg       string            `json:"arg"`
+	Subtitle  string            `json:"subtitle"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// ExpandHome turns a leading ~/ into the user's home folder
+func ExpandHome(filename string) string {
+	if strings.HasPrefix(filename, "~/") {
+		dir, _ := os.UserHomeDir()
+		return filepath.Join(dir, filename[2:])
+	}
+	return filename
+}
+
+func findMatchingFiles(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	var matches []string
+	var err error
+	terms := queryWords(searchTerm)
+	if config.Regex {
+		matches, err = listFiles(directory, searchTerm, config)
+	} else if len(terms) == 1 {
+		matches, err = listFiles(directory, terms[0], config)
+	} else {
+		matches, err = listFiles(directory, "", config)
+		if len(terms) > 1 {
+			// each word can match anywhere in the path, in any order
+			matches = filterByTerms(matches, terms, isCaseSensitive(searchTerm, config.Case), config.Synonyms)
+		}
+	}
+	if err != nil {
+		return AlfredResults{}, err
+	}
+
+	var alfredResults []AlfredResult
+	for _, match := range matches {
+		result := noteResult(match, directory, vault, config)
+		config.emit(result)
+		alfredResults = append(alfredResults, result)
+	}
+	// fd lists files in no particular order; most recently touched first is
+	// a better guess at what's wanted, and the notes opened most beat that
+	sortByModified(alfredResults)
+	sortByFrecency(alfredResults, loadVisits())
+
+	return AlfredResults{Items: alfredResults}, nil
+}
+
+// the filenames whose path contains every one of terms
+func filterByTerms(filenames []string, terms []string, caseSensitive bool, synonyms map[string]string) []string {
+	flags := "(?i)"
+	if caseSensitive {
+		flags = ""
+	}
+	patterns := make([]*regexp.Regexp, len(terms))
+	for index, term := range terms {
+		patterns[index] = regexp.MustCompile(flags + synonymPattern(term, synonyms, foldingPattern))
+	}
+
+	var matches []string
+	for _, filename := range filenames {
+		path := withoutMd(filename)
+		matchesAll := true
+		for _, pattern := range patterns {
+			if !pattern.MatchString(path) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			matches = append(matches, filename)
+		}
+	}
+	return matches
+}
+
+// like findMatchingFiles, but the characters of searchTerm only have to
+// appear in order, so "projalpharoad" finds "Projects/Alpha Roadmap.md"
+func fuzzyMatchingFiles(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	files, err := listFiles(directory, "", config)
+	if err != nil {
+		return AlfredResults{}, err
+	}
+	var alfredResults []AlfredResult
+	for _, match := range fuzzyFilter(searchTerm, files, isCaseSensitive(searchTerm, config.Case)) {
+		result := noteResult(match, directory, vault, config)
+		config.emit(result)
+		alfredResults = append(alfredResults, result)
+	}
+
+	return AlfredResults{Items: alfredResults}, nil
+}
+
+// list every note once, leaving the per-keystroke filtering to Alfred
+func listAllNotes(directory string, vault string, config Config) (AlfredResults, error) {
+	files, err := listFiles(directory, "", config)
+	if err != nil {
+		return AlfredResults{}, err
+	}
+	var alfredResults []AlfredResult
+	for _, match := range files {
+		result := noteResult(match, directory, vault, config)
+		result.Match = matchString(match, config)
+		config.emit(result)
+		alfredResults = append(alfredResults, result)
+	}
+
+	return AlfredResults{Items: alfredResults}, nil
+}
+
+// the fields every note result shares, whichever mode found it
+func noteResult(filename string, directory string, vault string, config Config) AlfredResult {
+	fullPath := filepath.Join(directory, filename)
+	title := displayTitle(filename, fullPath, config)
+	obsidianUrl := ObsidianUrl(filename, vault)
+	variables := map[string]string{
+		"vault":    vault,
+		"path":     filename,
+		"fullpath": fullPath,
+	}
+	return AlfredResult{
+		UID:          resultUid(filename, vault),
+		Type:         "default",
+		Title:        title,
+		Subtitle:     withFileName(title, filename, fileSubtitle(filename, fullPath)),
+		Arg:          obsidianUrl,
+		Autocomplete: fileTitle(filename),
+		QuicklookUrl: fullPath,
+		Text:         &AlfredText{Copy: obsidianUrl, LargeType: filename},
+		Icon:         resultIcon(filename, directory, config),
+		Variables:    variables,
+		Action:       &AlfredAction{File: fullPath},
+		Mods: map[string]AlfredMod{
+			"cmd":        modAction("reveal", fullPath, "Reveal in Finder", variables),
+			"alt":        modAction("copy", fullPath, "Copy file path", variables),
+			"ctrl":       modAction("copy", Wikilink(filename), "Copy wikilink", variables),
+			"ctrl+shift": modAction("copy", MarkdownLink(title, obsidianUrl), "Copy markdown link", variables),
+			"shift":      modAction("copy", obsidianUrl, "Copy Obsidian URL", variables),
+			"fn":         modAction("edit", fullPath, "Open in "+editorLabel(config), variables),
+			"cmd+alt":    modAction("contents", fullPath, "Copy the note's text", variables),
+		},
+	}
+}
+
+// where the note is and when it was last edited, to tell apart notes with
+// similar names
+func fileSubtitle(filename string, fullPath string) string {
+	var parts []string
+	if folder := filepath.Dir(filename); folder != "." {
+		parts = append(parts, toNFC(filepath.ToSlash(folder)))
+	}
+	if info, err := os.Stat(fullPath); err == nil {
+		parts = append(parts, "edited "+humanizeAge(info.ModTime(), time.Now()))
+	} else if isEvicted(fullPath) {
+		parts = append(parts, "in iCloud, not downloaded")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// VaultLabel is what results from the vault in directory are labelled
+// with: its folder's name, since the vault Obsidian URLs name may be an ID
+func VaultLabel(directory string) string {
+	return filepath.Base(filepath.Clean(directory))
+}
+
+// start each note's subtitle with the vault it's in
+func addVaultNames(results AlfredResults, directory string) {
+	label := VaultLabel(directory)
+	for index, result := range results.Items {
+		if len(result.Variables["path"]) == 0 {
+			continue
+		}
+		if len(result.Subtitle) > 0 {
+			results.Items[index].Subtitle = label + " · " + result.Subtitle
+		} else {
+			results.Items[index].Subtitle = label
+		}
+	}
+}
+
+// Alfred passes a modifier's variables instead of the item's, so each mod
+// carries its own copy of them
+func modAction(action string, arg string, subtitle string, variables map[string]string) AlfredMod {
+	modVariables := map[string]string{"action": action}
+	for key, value := range variables {
+		modVariables[key] = value
+	}
+	return AlfredMod{
+		Valid:     true,
+		Arg:       arg,
+		Subtitle:  subtitle,
+		Variables: modVariables,
+	}
+}
+
+func setVariable(result *AlfredResult, key string, value string) {
+	result.Variables[key] = value
+	for _, mod := range result.Mods {
+		mod.Variables[key] = value
+	}
+}
+
+// Wikilink links to a note the way Obsidian writes links
+func Wikilink(filename string) string {
+	return fmt.Sprintf("[[%s]]", toNFC(withoutMd(filepath.Base(filename))))
+}
+
+// MarkdownLink links to a note from outside Obsidian, as a markdown link
+// titled title that opens link
+func MarkdownLink(title string, link string) string {
+	text := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(title)
+	// a bracket in the URL would end the link early
+	link = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(link)
+	return fmt.Sprintf("[%s](%s)", text, link)
+}
+
+// interleave a header row for each folder, keeping folders in the order
+// their first result appeared
+func groupByFolder(results AlfredResults, vault string) AlfredResults {
+	var folders []string
+	groups := make(map[string][]AlfredResult)
+	for _, result := range results.Items {
+		folder := filepath.Dir(result.Variables["path"])
+		if _, ok := groups[folder]; !ok {
+			folders = append(folders, folder)
+		}
+		groups[folder] = append(groups[folder], result)
+	}
+
+	valid := false
+	var grouped []AlfredResult
+	for _, folder := range folders {
+		title := folder
+		if folder == "." {
+			title = vault
+		}
+		grouped = append(grouped, AlfredResult{
+			Type:     "default",
+			Valid:    &valid,
+			Title:    title,
+			Subtitle: fmt.Sprintf("%d in this folder", len(groups[folder])),
+			Icon:     &AlfredIcon{Type: "filetype", Path: "public.folder"},
+		})
+		grouped = append(grouped, groups[folder]...)
+	}
+	results.Items = grouped
+	return results
+}
+
+func listFiles(directory string, searchTerm string, config Config) ([]string, error) {
+	var pattern string
+	if len(searchTerm) > 0 {
+		pattern = searchTerm
+		if !config.Regex {
+			pattern = synonymPattern(searchTerm, config.Synonyms, foldingPattern)
+		}
+		pattern = withCase(pattern, searchTerm, config.Case)
+	}
+	// an iCloud vault's evicted notes are found from their placeholders, in
+	// the same listing as the rest
+	listing := config
+	listing.placeholders = isICloudVault(directory)
+	files, err := config.searchBackend().FindFiles(pattern, listing)
+	if err != nil || !listing.placeholders {
+		return files, err
+	}
+	files, placeholders := splitPlaceholders(files)
+	evicted, err := evictedNotesMatching(placeholders, pattern, config)
+	return append(files, evicted...), err
+}
+
+// the words Alfred should filter a note on: its title and initials, aliases
+// and tags
+func matchString(filename string, config Config) string {
+	title := toNFC(withoutMd(filepath.Base(filename)))
+	words := []string{title}
+	if folded := foldDiacritics(title); folded != title {
+		words = append(words, folded)
+	}
+	if acronym := initials(title); len([]rune(acronym)) > 1 {
+		words = append(words, acronym)
+	}
+	if strings.HasSuffix(filename, ".md") {
+		content, err := readNote(config.vaultPath(filename))
+		if err == nil {
+			frontmatter, body := parseFrontmatter(string(content))
+			words = append(words, frontmatter["title"]...)
+			if heading := firstHeading(body); len(heading) > 0 && heading != title {
+				words = append(words, heading)
+			}
+			words = append(words, frontmatter["aliases"]...)
+			words = append(words, frontmatter["alias"]...)
+			for _, tag := range noteTags(frontmatter, body) {
+				words = append(words, tag, "#"+tag)
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func withoutMd(filename string) string {
+	if strings.HasSuffix(filename, ".md") {
+		return filename[0 : len(filename)-3]
+	}
+	return filename
+}
+
+// a result that explains what went wrong instead of opening anything
+func errorResult(title string, subtitle string) AlfredResult {
+	valid := false
+	return AlfredResult{
+		Type:     "default",
+		Valid:    &valid,
+		Title:    title,
+		Subtitle: subtitle,
+		Icon:     &AlfredIcon{Path: AlertIcon},
+	}
+}
+
+// a stable identifier so Alfred can learn which notes get picked
+func resultUid(path string, vault string) string {
+	return vault + "/" + filepath.ToSlash(filepath.Clean(path))
+}
+
+// ObsidianLineUrl opens the note at a line, which needs the Advanced URI
+// plugin, naming it in composed form as ObsidianUrl does
+func ObsidianLineUrl(path string, vault string, line int) string {
+	return fmt.Sprintf("obsidian://advanced-uri?vault=%s&filepath=%s&line=%d", uriComponent(vault), uriComponent(toNFC(path)), line)
+}
+
+// s escaped for a value in an obsidian:// URL: spaces as %20, which
+// Obsidian reads back where it leaves a + alone, and the & and = that
+// PathEscape keeps but would end the value early
+func uriComponent(s string) string {
+	return strings.NewReplacer("&", "%26", "=", "%3D", "+", "%2B").Replace(url.PathEscape(s))
+}
+
+// point a result at one line of its note; the note's later lines are
+// indented under its first so they read as a group
+func linkToLine(result *AlfredResult, filename string, vault string, line int, continued bool) {
+	lineUrl := ObsidianLineUrl(filename, vault, line)
+	result.UID = fmt.Sprintf("%s:%d", result.UID, line)
+	result.Arg = lineUrl
+	result.Text.Copy = lineUrl
+	if shift, ok := result.Mods["shift"]; ok {
+		shift.Arg = lineUrl
+		result.Mods["shift"] = shift
+	}
+	if markdown, ok := result.Mods["ctrl+shift"]; ok {
+		markdown.Arg = MarkdownLink(result.Title, lineUrl)
+		result.Mods["ctrl+shift"] = markdown
+	}
+	if continued {
+		result.Title = "    ↳ " + result.Title
+		result.Autocomplete = ""
+	}
+}
+
+// ObsidianUrl opens the note in Obsidian, naming it in composed form
+// however macOS stored its name
+func ObsidianUrl(path string, vault string) string {
+	return fmt.Sprintf("obsidian://open?vault=%s&file=%s", vault, uriComponent(toNFC(path)))
+}
+
+// GetDefaults finds the name and folder of the vault open in Obsidian from
+// its config file
+func GetDefaults(obsidianConfig string) (string, string, error) {
+	content, err := ioutil.ReadFile(obsidianConfig)
+	if err != nil {
+		return "", "", exitError(ExitConfig, "could not open %s", obsidianConfig)
+	}
+	var result ObsidianConfig
+	err = json.Unmarshal(content, &result)
+	if err != nil {
+		return "", "", exitError(ExitConfig, "could not parse %s: %s", obsidianConfig, err)
+	}
+
+	for vaultId, vault := range result.Vaults {
+		if vault.Open {
+			return vaultId, vault.Path, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// FindVault finds the vault a file or folder is in by looking upward for
+// its .obsidian folder. It returns the vault's ID from Obsidian's config
+// file, or just its folder's name (which Obsidian URLs take too) if
+// Obsidian hasn't opened it, and its folder.
+func FindVault(path string, obsidianConfig string) (string, string, error) {
+	folder, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		info, err := os.Stat(filepath.Join(folder, ".obsidian"))
+		if err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(folder)
+		if parent == folder {
+			return "", "", fmt.Errorf("%s isn't in a vault", path)
+		}
+		folder = parent
+	}
+
+	var known ObsidianConfig
+	if content, err := ioutil.ReadFile(obsidianConfig); err == nil {
+		json.Unmarshal(content, &known)
+	}
+	for vaultId, vault := range known.Vaults {
+		if filepath.Clean(ExpandHome(vault.Path)) == folder {
+			return vaultId, folder, nil
+		}
+	}
+	return filepath.Base(folder), folder, nil
+}
+
+func grepMatchingFiles(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	query := &queryNode{op: opTerm, term: searchTerm}
+	if !config.Regex {
+		var err error
+		query, err = parseQuery(searchTerm)
+		if err != nil {
+			// most likely still being typed, like "(draft"
+			return AlfredResults{Items: []AlfredResult{errorResult("Can't search for "+searchTerm+" yet", err.Error())}}, nil
+		}
+	}
+	terms := query.positiveTerms()
+
+	backend := config.searchBackend()
+	var found []LineMatch
+	var allowed map[string]bool
+	var err error
+	if query.op == opTerm {
+		found, err = backend.GrepContent(termPattern(query.term, config), config)
+	} else {
+		// one pass per term to decide which files match, then another for
+		// the lines to show from them
+		var allFiles map[string]bool
+		if query.hasNot() {
+			// fd's extra arguments are for file name searches
+			everything := config
+			everything.FdArgs = nil
+			files, err := backend.FindFiles("", everything)
+			if err != nil {
+				return AlfredResults{}, err
+			}
+			allFiles = make(map[string]bool)
+			for _, file := range files {
+				allFiles[file] = true
+			}
+		}
+		allowed = query.evaluate(queryEnv{
+			filesWith: func(term string) map[string]bool {
+				files, termErr := backend.FilesContaining(termPattern(term, config), config)
+				if termErr != nil && err == nil {
+					err = termErr
+				}
+				return files
+			},
+			allFiles: allFiles,
+			near: func(file string, a string, b string, distance int) bool {
+				return fileHasNear(file, a, b, distance, config)
+			},
+		})
+		alternatives := make([]string, len(terms))
+		for index, term := range terms {
+			alternatives[index] = termPattern(term, config)
+		}
+		if err == nil {
+			found, err = backend.GrepContent(strings.Join(alternatives, "|"), config)
+		}
+	}
+	if err != nil {
+		return AlfredResults{}, err
+	}
+
+	var matches []*fileMatches
+	byFile := make(map[string]*fileMatches)
+	regions := make(map[string]noteRegions)
+	for _, match := range found {
+		filename := match.Path
+		if allowed != nil && !allowed[filename] {
+			continue
+		}
+		if config.Frontmatter || config.NoCode || config.NoFrontmatter {
+			if _, ok := regions[filename]; !ok {
+				regions[filename] = readRegions(config.vaultPath(filename))
+			}
+			if regions[filename].excludes(match.Number, config) {
+				continue
+			}
+		}
+		m, ok := byFile[filename]
+		if !ok {
+			m = &fileMatches{filename: filename}
+			byFile[filename] = m
+			matches = append(matches, m)
+		}
+		m.lines = append(m.lines, matchedLine{text: match.Text, number: match.Number, start: match.Start, end: match.End})
+		if isHeading(match.Text) {
+			m.headingMatches++
+		} else {
+			m.bodyMatches++
+		}
+	}
+
+	rankMatches(matches, terms, vault, config, loadVisits())
+	if config.Count {
+		return AlfredResults{Items: countResults(matches, terms, directory, vault, config)}, nil
+	}
+
+	var results []AlfredResult
+	for _, m := range matches {
+		for index, line := range shownLines(m, terms, config) {
+			result := noteResult(m.filename, directory, vault, config)
+			result.Subtitle = withFileName(result.Title, m.filename, lineSnippet(line, terms, config))
+			result.Text.LargeType = strings.TrimSpace(line.text)
+			setVariable(&result, "line", strconv.Itoa(line.number))
+			if contents, ok := result.Mods["cmd+alt"]; ok {
+				contents.Subtitle = "Copy this section of the note"
+				result.Mods["cmd+alt"] = contents
+			}
+			if config.PerFile > 1 || config.Dedupe == DedupeOff {
+				linkToLine(&result, m.filename, vault, line.number, index > 0)
+			}
+			config.emit(result)
+			results = append(results, result)
+		}
+	}
+
+	return AlfredResults{
+		Items: results,
+	}, nil
+}
+
+// search file names and contents at once: notes whose names match come
+// first, then the rest of the notes with matching lines
+func bothMatchingFiles(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	results, err := findMatchingFiles(searchTerm, directory, vault, config)
+	if err != nil || !isCompleteQuery(searchTerm, config) {
+		return results, err
+	}
+	named := make(map[string]bool)
+	for _, result := range results.Items {
+		named[result.Variables["path"]] = true
+	}
+	if found := config.found; found != nil {
+		config.found = func(result AlfredResult) {
+			if !named[result.Variables["path"]] {
+				found(result)
+			}
+		}
+	}
+	contents, err := grepMatchingFiles(searchTerm, directory, vault, config)
+	for _, result := range contents.Items {
+		if !named[result.Variables["path"]] {
+			results.Items = append(results.Items, result)
+		}
+	}
+	return results, err
+}
+
+// whether grepMatchingFiles can make sense of searchTerm, so searches by
+// name only look in the text as well once it's been typed in full
+func isCompleteQuery(searchTerm string, config Config) bool {
+	if config.Regex {
+		return true
+	}
+	_, err := parseQuery(searchTerm)
+	return err == nil
+}
+
+// when no file names match, the notes that mention the search instead,
+// marked so it's clear that's where they matched
+func contentFallback(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	if !isCompleteQuery(searchTerm, config) {
+		return AlfredResults{}, nil
+	}
+	if found := config.found; found != nil {
+		config.found = func(result AlfredResult) {
+			result.Subtitle = "In text: " + result.Subtitle
+			found(result)
+		}
+	}
+	results, err := grepMatchingFiles(searchTerm, directory, vault, config)
+	for index := range results.Items {
+		results.Items[index].Subtitle = "In text: " + results.Items[index].Subtitle
+	}
+	return results, err
+}
+
+const ObsidianConfigFile = "~/Library/Application Support/obsidian/obsidian.json"
+
+// contentPattern with its case sensitivity built in, for combining with
+// other terms into one regex
+func termPattern(term string, config Config) string {
+	if config.Regex {
+		return withCase(contentPattern(term, config), term, config.Case)
+	}
+	return withCase(synonymPattern(term, config.Synonyms, func(word string) string {
+		return contentPattern(word, config)
+	}), term, config.Case)
+}
@@ -0,0 +1,335 @@
+package osearch
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the bundle ID of the packaged workflow, which also names its data folder
+const WorkflowBundleId = "com.disser.osearch"
+
+// the icon Obsidian ships, turned into the workflow's when no other is given
+const obsidianIcon = ObsidianApp + "/Contents/Resources/icon.icns"
+
+// the modifier bits Alfred gives each key in a connection
+const (
+	commandModifier = 1048576
+	altModifier     = 524288
+	controlModifier = 262144
+	shiftModifier   = 131072
+	fnModifier      = 8388608
+)
+
+// the keys results have a mod for, alone or together, and none at all
+var resultModifiers = []int{0, commandModifier, altModifier, controlModifier, shiftModifier, fnModifier, commandModifier | altModifier, controlModifier | shiftModifier}
+
+// a Script Filter the packaged workflow has for a mode
+type workflowSearch struct {
+	keyword string
+	title   string
+	flags   string
+	// whether Alfred filters the results itself, for --list
+	alfredFilters bool
+}
+
+var workflowSearches = []workflowSearch{
+	{"o", "Search note names", "", false},
+	{"of", "Search note names fuzzily", "--fuzzy", false},
+	{"og", "Search note contents", "--grep", false},
+	{"ob", "Search note names and contents", "--both", false},
+	{"ofm", "Search frontmatter", "--frontmatter", false},
+	{"ol", "Pick from every note", "--list", true},
+}
+
+// PackageWorkflow writes an .alfredworkflow bundle to out that Alfred
+// imports with a double click: a Script Filter for each mode searching the
+// vault open in Obsidian, doing what the chosen result's action variable
+// says, from opening the note and recording the visit to copying its text
+// or a link to it, with binary as the osearch it runs. icon is a PNG;
+// empty means Obsidian's icon, if sips can convert it.
+func PackageWorkflow(out string, binary string, icon string) error {
+	program, err := ioutil.ReadFile(binary)
+	if err != nil {
+		return err
+	}
+	var iconData []byte
+	if len(icon) > 0 {
+		iconData, err = ioutil.ReadFile(icon)
+		if err != nil {
+			return err
+		}
+	} else {
+		iconData = obsidianPng()
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	bundle := zip.NewWriter(file)
+	objects, connections, positions, uids := workflowObjects()
+	files := map[string][]byte{"info.plist": []byte(workflowPlist(objects, connections, positions))}
+	if len(iconData) > 0 {
+		files["icon.png"] = iconData
+		for _, uid := range uids {
+			files[uid+".png"] = iconData
+		}
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err = writeZipFile(bundle, name, 0644, files[name])
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writeZipFile(bundle, "osearch", 0755, program)
+	}
+	if closeErr := bundle.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeZipFile(bundle *zip.Writer, name string, mode os.FileMode, content []byte) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	header.SetMode(mode)
+	writer, err := bundle.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
+
+// Obsidian's icon as a PNG, or nothing off a Mac or without Obsidian
+func obsidianPng() []byte {
+	folder, err := ioutil.TempDir("", "osearch-icon")
+	if err != nil {
+		return nil
+	}
+	defer os.RemoveAll(folder)
+	png := filepath.Join(folder, "icon.png")
+	_, err = commandOutput(exec.Command("sips", "-s", "format", "png", "-Z", "256", obsidianIcon, "--out", png))
+	if err != nil {
+		return nil
+	}
+	content, _ := ioutil.ReadFile(png)
+	return content
+}
+
+// the workflow's Script Filters, the Conditional branching on the chosen
+// result's action variable, the action opening the note, the scripts
+// recording the visit, revealing the note, opening it in the editor and
+// getting its text for the clipboard, how they're wired together, where
+// they sit on the canvas and the UIDs of the Script Filters
+func workflowObjects() ([]interface{}, map[string]interface{}, map[string]interface{}, []string) {
+	const actionUid = "osearch.action"
+	const openUid = "osearch.open"
+	const recordUid = "osearch.record"
+	const revealUid = "osearch.reveal"
+	const editUid = "osearch.edit"
+	const contentsUid = "osearch.contents"
+	const clipboardUid = "osearch.clipboard"
+	var objects []interface{}
+	connections := map[string]interface{}{}
+	positions := map[string]interface{}{}
+	var uids []string
+	for index, search := range workflowSearches {
+		uid := "osearch." + search.keyword
+		uids = append(uids, uid)
+		script := strings.TrimSpace("./osearch " + search.flags + ` "$1"`)
+		if search.alfredFilters {
+			script = "./osearch " + search.flags
+		}
+		objects = append(objects, map[string]interface{}{
+			"type":    "alfred.workflow.input.scriptfilter",
+			"uid":     uid,
+			"version": 3,
+			"config": map[string]interface{}{
+				"keyword":                        search.keyword,
+				"title":                          search.title,
+				"runningsubtext":                 "Searching…",
+				"withspace":                      true,
+				"argumenttype":                   1,
+				"type":                           0,
+				"scriptargtype":                  1,
+				"script":                         script,
+				"alfredfiltersresults":           search.alfredFilters,
+				"queuemode":                      1,
+				"queuedelaycustom":               3,
+				"queuedelaymode":                 0,
+				"queuedelayimmediatelyinitially": true,
+			},
+		})
+		// whichever key is held, the result's mod says what happens and sets
+		// action to match, so every key goes to the Conditional
+		var toAction []interface{}
+		for _, modifiers := range resultModifiers {
+			connection := workflowConnection(actionUid)
+			connection["modifiers"] = modifiers
+			toAction = append(toAction, connection)
+		}
+		connections[uid] = toAction
+		positions[uid] = map[string]interface{}{"xpos": 30, "ypos": 15 + 120*index}
+	}
+
+	// each action a result can set goes to what does it; anything else,
+	// open included, opens the note in Obsidian
+	branches := []struct {
+		action      string
+		destination string
+	}{
+		{"edit", editUid},
+		{"contents", contentsUid},
+		{"copy", clipboardUid},
+		{"reveal", revealUid},
+	}
+	var conditions []interface{}
+	var fromAction []interface{}
+	for _, branch := range branches {
+		outputUid := actionUid + "." + branch.action
+		conditions = append(conditions, map[string]interface{}{
+			"inputstring":        "{var:action}",
+			"matchcasesensitive": false,
+			"matchmode":          0,
+			"matchstring":        branch.action,
+			"outputlabel":        branch.action,
+			"uid":                outputUid,
+		})
+		connection := workflowConnection(branch.destination)
+		connection["sourceoutputuid"] = outputUid
+		fromAction = append(fromAction, connection)
+	}
+	// the else output is the one connections leave without a sourceoutputuid
+	connections[actionUid] = append(fromAction, workflowConnection(openUid), workflowConnection(recordUid))
+
+	objects = append(objects,
+		map[string]interface{}{
+			"type":    "alfred.workflow.utility.conditional",
+			"uid":     actionUid,
+			"version": 1,
+			"config":  map[string]interface{}{"conditions": conditions, "elselabel": "open", "hideelse": false},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.action.openurl",
+			"uid":     openUid,
+			"version": 1,
+			"config":  map[string]interface{}{"url": "{query}"},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.action.script",
+			"uid":     recordUid,
+			"version": 2,
+			"config":  map[string]interface{}{"type": 0, "scriptargtype": 1, "script": `./osearch record --vault "$vault" "$path"`},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.action.script",
+			"uid":     revealUid,
+			"version": 2,
+			"config":  map[string]interface{}{"type": 0, "scriptargtype": 1, "script": `open -R "$fullpath"`},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.action.script",
+			"uid":     editUid,
+			"version": 2,
+			"config":  map[string]interface{}{"type": 0, "scriptargtype": 1, "script": `./osearch edit "$fullpath"`},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.action.script",
+			"uid":     contentsUid,
+			"version": 2,
+			"config":  map[string]interface{}{"type": 0, "scriptargtype": 1, "script": `./osearch contents ${line:+--line "$line"} "$fullpath"`},
+		},
+		map[string]interface{}{
+			"type":    "alfred.workflow.output.clipboard",
+			"uid":     clipboardUid,
+			"version": 3,
+			"config":  map[string]interface{}{"clipboardtext": "{query}", "autopaste": false},
+		})
+	connections[contentsUid] = []interface{}{workflowConnection(clipboardUid)}
+	positions[actionUid] = map[string]interface{}{"xpos": 230, "ypos": 255}
+	positions[openUid] = map[string]interface{}{"xpos": 400, "ypos": 15}
+	positions[recordUid] = map[string]interface{}{"xpos": 400, "ypos": 135}
+	positions[revealUid] = map[string]interface{}{"xpos": 400, "ypos": 255}
+	positions[editUid] = map[string]interface{}{"xpos": 400, "ypos": 375}
+	positions[contentsUid] = map[string]interface{}{"xpos": 400, "ypos": 495}
+	positions[clipboardUid] = map[string]interface{}{"xpos": 600, "ypos": 495}
+	return objects, connections, positions, uids
+}
+
+// a connection to destination that is followed when no modifier is held
+func workflowConnection(destination string) map[string]interface{} {
+	return map[string]interface{}{"destinationuid": destination, "modifiers": 0, "modifiersubtext": "", "vitoclose": false}
+}
+
+func workflowPlist(objects []interface{}, connections map[string]interface{}, positions map[string]interface{}) string {
+	var plist strings.Builder
+	plist.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`)
+	writePlistValue(&plist, map[string]interface{}{
+		"bundleid":    WorkflowBundleId,
+		"name":        "Obsidian Search",
+		"description": "Search your Obsidian vault with osearch",
+		"createdby":   "disser",
+		"webaddress":  "https://github.com/disser/alfred-obsidian-search",
+		"version":     strings.TrimPrefix(Version, "v"),
+		"readme":      "Searches the vault open in Obsidian. Set a vault with --vault and --path in each Script Filter to search another.",
+		"objects":     objects,
+		"connections": connections,
+		"uidata":      positions,
+	}, "")
+	plist.WriteString("</plist>\n")
+	return plist.String()
+}
+
+// write a value out as plist XML, with maps as dicts in key order
+func writePlistValue(plist *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		plist.WriteString(indent + "<dict>\n")
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			plist.WriteString(indent + "\t<key>" + html.EscapeString(key) + "</key>\n")
+			writePlistValue(plist, v[key], indent+"\t")
+		}
+		plist.WriteString(indent + "</dict>\n")
+	case []interface{}:
+		plist.WriteString(indent + "<array>\n")
+		for _, item := range v {
+			writePlistValue(plist, item, indent+"\t")
+		}
+		plist.WriteString(indent + "</array>\n")
+	case string:
+		plist.WriteString(indent + "<string>" + html.EscapeString(v) + "</string>\n")
+	case int:
+		plist.WriteString(fmt.Sprintf("%s<integer>%d</integer>\n", indent, v))
+	case bool:
+		if v {
+			plist.WriteString(indent + "<true/>\n")
+		} else {
+			plist.WriteString(indent + "<false/>\n")
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package osearch
+
+import (
+	"testing"
+)
+
+// where the packaged workflow sends a result picked with a mod's variables,
+// following the Conditional on its action
+func workflowDestinations(variables map[string]string) []string {
+	objects, connections, _, _ := workflowObjects()
+	var conditions []interface{}
+	for _, object := range objects {
+		if object := object.(map[string]interface{}); object["uid"] == "osearch.action" {
+			conditions = object["config"].(map[string]interface{})["conditions"].([]interface{})
+		}
+	}
+	output := ""
+	for _, condition := range conditions {
+		condition := condition.(map[string]interface{})
+		if condition["matchstring"] == variables["action"] {
+			output = condition["uid"].(string)
+		}
+	}
+	var destinations []string
+	for _, connection := range connections["osearch.action"].([]interface{}) {
+		connection := connection.(map[string]interface{})
+		source, _ := connection["sourceoutputuid"].(string)
+		if source == output {
+			destinations = append(destinations, connection["destinationuid"].(string))
+		}
+	}
+	return destinations
+}
+
+func TestWorkflowRoutesActions(t *testing.T) {
+	directory := testVault(t, map[string]string{"Note.md": "text\n"})
+	tests := []struct {
+		action string
+		key    string
+		want   string
+	}{
+		{"", "", "osearch.open"},
+		{"", "fn", "osearch.edit"},
+		{"", "cmd", "osearch.reveal"},
+		{"", "cmd+alt", "osearch.contents"},
+		{"", "ctrl", "osearch.clipboard"},
+		{ActionEditor, "", "osearch.edit"},
+		{ActionEditor, "fn", "osearch.open"},
+		{ActionContents, "", "osearch.contents"},
+		{ActionWikilink, "", "osearch.clipboard"},
+		{ActionMarkdownLink, "ctrl+shift", "osearch.open"},
+	}
+	for _, test := range tests {
+		results := []AlfredResult{noteResult("Note.md", directory, "vault", Config{directory: directory})}
+		if key, ok := actionMods[test.action]; ok {
+			withMainAction(results, key)
+		}
+		result := results[0]
+		variables := result.Variables
+		if len(test.key) > 0 {
+			variables = result.Mods[test.key].Variables
+		}
+		destinations := workflowDestinations(variables)
+		if len(destinations) == 0 || destinations[0] != test.want {
+			t.Errorf("--action %q with %q goes to %q, want %s", test.action, test.key, destinations, test.want)
+		}
+	}
+}
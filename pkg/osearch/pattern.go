@@ -0,0 +1,46 @@
+package osearch
+
+import (
+	"regexp"
+	"strings"
+)
+
+// contentPattern is the regex rg searches for. With --regex that's the query
+// as typed. Otherwise the query is taken literally, as rg -F would, except
+// that letters match regardless of accents and, with typos or stemming
+// turned on, each word is expanded. Searching multiline, any space in
+// the query matches a line break too.
+func contentPattern(query string, config Config) string {
+	if config.Regex {
+		return query
+	}
+	if config.Multiline {
+		words := strings.Fields(query)
+		single := config
+		single.Multiline = false
+		for index, word := range words {
+			words[index] = contentPattern(word, single)
+		}
+		return strings.Join(words, `\s+`)
+	}
+	if config.Typos == 0 && !config.Stem {
+		return foldingPattern(query)
+	}
+
+	var pattern strings.Builder
+	last := 0
+	for _, span := range tokenSpans(query) {
+		pattern.WriteString(regexp.QuoteMeta(query[last:span[0]]))
+		pattern.WriteString(wordPattern(query[span[0]:span[1]], config))
+		last = span[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(query[last:]))
+	return pattern.String()
+}
+
+func wordPattern(word string, config Config) string {
+	if !config.Stem || isCJK([]rune(word)[0]) {
+		return typoTolerantWord(word, config.Typos)
+	}
+	return `\b` + typoTolerantWord(stemWithCase(word), config.Typos) + `\p{L}*`
+}
@@ -0,0 +1,80 @@
+package osearch
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTermPattern(t *testing.T) {
+	tests := []struct {
+		name   string
+		term   string
+		config Config
+		text   string
+		want   bool
+	}{
+		{"literal", "a.b", Config{}, "a.b", true},
+		{"literal dot", "a.b", Config{}, "axb", false},
+		{"smart case lower", "kube", Config{}, "Kubernetes", true},
+		{"smart case upper", "Kube", Config{}, "kubernetes", false},
+		{"ignore case", "Kube", Config{Case: CaseInsensitive}, "kubernetes", true},
+		{"accents", "cafe", Config{}, "Café au lait", true},
+		{"regex", "a.b", Config{Regex: true}, "axb", true},
+		{"stem", "deploying", Config{Stem: true}, "deployed today", true},
+		{"stem keeps case", "Kubernetes", Config{Stem: true}, "Kubernetes deploys", true},
+		{"typo", "kubernetse", Config{Typos: 1}, "kubernetes", true},
+		{"too many typos", "kbrnetse", Config{Typos: 1}, "kubernetes", false},
+		{"multiline", "end start", Config{Multiline: true}, "the end\nstart", true},
+		{"synonym", "k8s", Config{Synonyms: map[string]string{"k8s": "kubernetes"}}, "Kubernetes deploys", true},
+		{"synonym keeps word", "k8s", Config{Synonyms: map[string]string{"k8s": "kubernetes"}}, "k8s deploys", true},
+		{"lone combining mark", "\u0301", Config{Stem: true, Typos: 2}, "cafe\u0301", true},
+		{"legacy synonym", "K8S", Config{Synonyms: map[string]string{"k8s": "(k8s OR kubernetes)"}}, "kubernetes", true},
+	}
+	for _, test := range tests {
+		pattern := termPattern(test.term, test.config)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			t.Errorf("%s: %s doesn't compile: %s", test.name, pattern, err)
+			continue
+		}
+		if got := re.MatchString(test.text); got != test.want {
+			t.Errorf("%s: %s matching %q = %v, want %v", test.name, pattern, test.text, got, test.want)
+		}
+	}
+}
+
+func TestStemWithCase(t *testing.T) {
+	tests := map[string]string{
+		"Kubernetes": "Kubernete",
+		"meetings":   "meet",
+		"Running":    "Run",
+	}
+	for word, want := range tests {
+		if got := stemWithCase(word); got != want {
+			t.Errorf("stemWithCase(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestSynonymAlternatives(t *testing.T) {
+	synonyms := map[string]string{"k8s": "kubernetes", "mtg": `("meeting" OR mtg OR call)`}
+	tests := map[string][]string{
+		"k8s":  {"k8s", "kubernetes"},
+		"K8s":  {"K8s", "kubernetes"},
+		"mtg":  {"mtg", "meeting", "call"},
+		"note": {"note"},
+	}
+	for word, want := range tests {
+		got := synonymAlternatives(word, synonyms)
+		if len(got) != len(want) {
+			t.Errorf("synonymAlternatives(%q) = %q, want %q", word, got, want)
+			continue
+		}
+		for index := range want {
+			if got[index] != want[index] {
+				t.Errorf("synonymAlternatives(%q) = %q, want %q", word, got, want)
+				break
+			}
+		}
+	}
+}
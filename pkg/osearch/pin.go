@@ -0,0 +1,58 @@
+package osearch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// pinned in the config file, or with "pinned: true" in its own frontmatter
+func isPinned(filename string, fullPath string, pinned []string) bool {
+	for _, pin := range pinned {
+		if filepath.Clean(pin) == filepath.Clean(filename) {
+			return true
+		}
+	}
+	if !strings.HasSuffix(filename, ".md") {
+		return false
+	}
+	content, err := readNote(fullPath)
+	if err != nil {
+		return false
+	}
+	frontmatter, _ := parseFrontmatter(string(content))
+	values := frontmatter["pinned"]
+	return len(values) == 1 && strings.EqualFold(values[0], "true")
+}
+
+// move pinned notes to the top, keeping the order within each part
+func pinFirst(results []AlfredResult, pinned []string) []AlfredResult {
+	var first, rest []AlfredResult
+	for _, result := range results {
+		if isPinned(result.Variables["path"], result.Variables["fullpath"], pinned) {
+			first = append(first, result)
+		} else {
+			rest = append(rest, result)
+		}
+	}
+	return append(first, rest...)
+}
+
+// Pin adds the note at path to the pinned notes in the config file, or
+// with remove takes it off them
+func Pin(configFile string, path string, remove bool) error {
+	path = filepath.Clean(path)
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	pinned := []string{}
+	for _, pin := range config.Pinned {
+		if filepath.Clean(pin) != path {
+			pinned = append(pinned, pin)
+		}
+	}
+	if !remove {
+		pinned = append(pinned, path)
+	}
+	return updateConfig(configFile, "pinned", pinned)
+}
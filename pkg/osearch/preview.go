@@ -0,0 +1,241 @@
+package osearch
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const previewStyle = `body { font: 14px -apple-system, sans-serif; max-width: 46em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 0.8em; overflow-x: auto; }
+code { font-family: Menlo, monospace; font-size: 90%; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #555; }
+img { max-width: 100%; }
+a.internal { color: #705dcf; }`
+
+var (
+	embedPattern    = regexp.MustCompile(`!\[\[([^\]|]+)(?:\|[^\]]*)?\]\]`)
+	imagePattern    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	wikilinkPattern = regexp.MustCompile(`\[\[([^\]|]+)(?:\|([^\]]+))?\]\]`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	codePattern     = regexp.MustCompile("`([^`]+)`")
+	boldPattern     = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	italicPattern   = regexp.MustCompile(`(^|[^\w*])[*_]([^*_]+)[*_]`)
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listPattern     = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	taskPattern     = regexp.MustCompile(`^\[([ xX])\]\s+`)
+)
+
+// write the note out as a standalone HTML page for Quick Look, reusing the
+// page from a previous run if the note hasn't changed since
+func renderPreview(source string, directory string, vault string) (string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+
+	previewDir := filepath.Join(os.TempDir(), "osearch-preview")
+	err = os.MkdirAll(previewDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	preview := filepath.Join(previewDir, fmt.Sprintf("%x.html", sha1.Sum([]byte(source))))
+	if cached, err := os.Stat(preview); err == nil && cached.ModTime().After(info.ModTime()) {
+		return preview, nil
+	}
+
+	content, err := readNote(source)
+	if err != nil {
+		return "", err
+	}
+	_, body := parseFrontmatter(string(content))
+
+	page := fmt.Sprintf("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title><style>%s</style></head><body>\n<h1>%s</h1>\n%s</body></html>\n",
+		html.EscapeString(withoutMd(filepath.Base(source))),
+		previewStyle,
+		html.EscapeString(withoutMd(filepath.Base(source))),
+		markdownToHtml(body, filepath.Dir(source), directory, vault))
+	err = ioutil.WriteFile(preview, []byte(page), 0600)
+	if err != nil {
+		return "", err
+	}
+	return preview, nil
+}
+
+// a deliberately small markdown renderer: enough block structure to make a
+// note readable, not a CommonMark implementation
+func markdownToHtml(markdown string, noteDir string, directory string, vault string) string {
+	var out strings.Builder
+	var paragraph []string
+	inCode := false
+	inList := false
+	inQuote := false
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = nil
+		}
+		if inList {
+			out.WriteString("</ul>\n")
+			inList = false
+		}
+		if inQuote {
+			out.WriteString("</blockquote>\n")
+			inQuote = false
+		}
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				flush()
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 {
+			flush()
+			continue
+		}
+
+		if match := headingPattern.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", len(match[1]), renderInline(match[2], noteDir, directory, vault), len(match[1])))
+		} else if trimmed == "---" || trimmed == "***" {
+			flush()
+			out.WriteString("<hr>\n")
+		} else if match := listPattern.FindStringSubmatch(line); match != nil {
+			if !inList {
+				flush()
+				out.WriteString("<ul>\n")
+				inList = true
+			}
+			item := match[1]
+			if task := taskPattern.FindStringSubmatch(item); task != nil {
+				checked := ""
+				if task[1] != " " {
+					checked = " checked"
+				}
+				item = fmt.Sprintf("<input type=\"checkbox\" disabled%s> %s", checked, renderInline(item[len(task[0]):], noteDir, directory, vault))
+			} else {
+				item = renderInline(item, noteDir, directory, vault)
+			}
+			out.WriteString("<li>" + item + "</li>\n")
+		} else if strings.HasPrefix(trimmed, ">") {
+			if !inQuote {
+				flush()
+				out.WriteString("<blockquote>\n")
+				inQuote = true
+			}
+			out.WriteString(renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")), noteDir, directory, vault) + "<br>\n")
+		} else {
+			if inList || inQuote {
+				flush()
+			}
+			paragraph = append(paragraph, renderInline(trimmed, noteDir, directory, vault))
+		}
+	}
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+	flush()
+	return out.String()
+}
+
+func renderInline(text string, noteDir string, directory string, vault string) string {
+	text = html.EscapeString(text)
+	text = embedPattern.ReplaceAllStringFunc(text, func(embed string) string {
+		target := html.UnescapeString(embedPattern.FindStringSubmatch(embed)[1])
+		if isImage(target) {
+			return fmt.Sprintf("<img src=\"%s\">", html.EscapeString(resolveAttachment(target, noteDir, directory)))
+		}
+		return fmt.Sprintf("<a class=\"internal\" href=\"%s\">%s</a>", html.EscapeString(ObsidianUrl(target, vault)), html.EscapeString(target))
+	})
+	text = imagePattern.ReplaceAllStringFunc(text, func(image string) string {
+		match := imagePattern.FindStringSubmatch(image)
+		src := html.UnescapeString(match[2])
+		if !strings.Contains(src, "://") {
+			src = resolveAttachment(src, noteDir, directory)
+		}
+		return fmt.Sprintf("<img alt=\"%s\" src=\"%s\">", match[1], html.EscapeString(src))
+	})
+	text = wikilinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		match := wikilinkPattern.FindStringSubmatch(link)
+		target := html.UnescapeString(match[1])
+		label := match[1]
+		if len(match[2]) > 0 {
+			label = match[2]
+		}
+		note := strings.SplitN(target, "#", 2)[0]
+		if !strings.Contains(filepath.Base(note), ".") {
+			note += ".md"
+		}
+		return fmt.Sprintf("<a class=\"internal\" href=\"%s\">%s</a>", html.EscapeString(ObsidianUrl(note, vault)), label)
+	})
+	text = linkPattern.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = codePattern.ReplaceAllString(text, "<code>$1</code>")
+	text = boldPattern.ReplaceAllString(text, "<strong>$2</strong>")
+	text = italicPattern.ReplaceAllString(text, "$1<em>$2</em>")
+	return text
+}
+
+func isImage(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp", ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// Obsidian resolves attachments next to the note first, then in the
+// attachment folder, then from the vault root
+func resolveAttachment(target string, noteDir string, directory string) string {
+	candidates := []string{
+		filepath.Join(noteDir, target),
+		filepath.Join(attachmentFolder(directory, noteDir), target),
+		filepath.Join(directory, target),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return fileUrl(candidate)
+		}
+	}
+	return fileUrl(filepath.Join(directory, target))
+}
+
+func fileUrl(path string) string {
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// swap each markdown result's Quick Look target for a rendered page
+func addHtmlPreviews(results AlfredResults, directory string, vault string) {
+	for index, result := range results.Items {
+		if !strings.HasSuffix(result.QuicklookUrl, ".md") {
+			continue
+		}
+		preview, err := renderPreview(result.QuicklookUrl, directory, vault)
+		if err != nil {
+			log.Printf("could not render a preview of %s: %s", result.QuicklookUrl, err)
+			continue
+		}
+		results.Items[index].QuicklookUrl = preview
+	}
+}
@@ -0,0 +1,304 @@
+package osearch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// a parsed content search: a term to look for, or AND, OR or NOT applied
+// to the nodes under it
+type queryNode struct {
+	op       string
+	term     string
+	children []*queryNode
+	// for NEAR, how many words apart its terms may be
+	distance int
+}
+
+const (
+	opTerm = "TERM"
+	opAnd  = "AND"
+	opOr   = "OR"
+	opNot  = "NOT"
+	opNear = "NEAR"
+)
+
+// the N in NEAR/N, if token is one
+func nearDistance(token string) (int, bool) {
+	if !strings.HasPrefix(token, opNear+"/") {
+		return 0, false
+	}
+	distance, err := strconv.Atoi(token[len(opNear)+1:])
+	return distance, err == nil && distance >= 0
+}
+
+// a word, phrase, operator or parenthesis in a query
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+func (t queryToken) isOperator() bool {
+	if t.quoted {
+		return false
+	}
+	switch t.text {
+	case opAnd, opOr, opNot, "(", ")":
+		return true
+	}
+	_, ok := nearDistance(t.text)
+	return ok
+}
+
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+// split a query into words, "quoted phrases", parentheses and operators
+func lexQuery(query string) []queryToken {
+	var tokens []queryToken
+	var word []rune
+	endWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, queryToken{text: string(word)})
+			word = nil
+		}
+	}
+
+	runes := []rune(query)
+	for index := 0; index < len(runes); index++ {
+		switch r := runes[index]; {
+		case r == '"':
+			endWord()
+			end := index + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if phrase := strings.TrimSpace(string(runes[index+1 : end])); len(phrase) > 0 {
+				tokens = append(tokens, queryToken{text: phrase, quoted: true})
+			}
+			index = end
+		case r == '(' || r == ')':
+			endWord()
+			tokens = append(tokens, queryToken{text: string(r)})
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			word = append(word, r)
+		}
+	}
+	endWord()
+	return tokens
+}
+
+// queryWords splits a query into its words, keeping "quoted phrases" whole
+func queryWords(query string) []string {
+	var words []string
+	for _, token := range lexQuery(query) {
+		words = append(words, token.text)
+	}
+	return words
+}
+
+// parseQuery understands AND, OR, NOT and NEAR/N (in capitals) and
+// parentheses, so "budget AND (2024 OR 2025) NOT draft" works, as does
+// "budget NEAR/5 forecast" for notes where the two are within five words.
+// Words next to each other must all appear, anywhere in the note; "quoted
+// phrases" must appear as typed.
+func parseQuery(query string) (*queryNode, error) {
+	tokens := lexQuery(query)
+	if len(tokens) == 0 {
+		return nil, errors.New("there's nothing to search for")
+	}
+	if len(tokens) == 1 && !tokens[0].isOperator() {
+		return &queryNode{op: opTerm, term: tokens[0].text}, nil
+	}
+
+	parser := &queryParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.next < len(tokens) {
+		return nil, fmt.Errorf("unexpected %s", tokens[parser.next].text)
+	}
+	return node, nil
+}
+
+// the next operator or parenthesis, or "" for a term or the end
+func (p *queryParser) peek() string {
+	if p.next < len(p.tokens) && p.tokens[p.next].isOperator() {
+		return p.tokens[p.next].text
+	}
+	return ""
+}
+
+func (p *queryParser) atEnd() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *queryParser) parseOr() (*queryNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == opOr {
+		p.next++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &queryNode{op: opOr, children: []*queryNode{node, right}}
+	}
+	return node, nil
+}
+
+func (p *queryParser) parseAnd() (*queryNode, error) {
+	node, err := p.parseNear()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := p.peek()
+		if next == opAnd {
+			p.next++
+		} else if p.atEnd() || next == opOr || next == ")" {
+			return node, nil
+		}
+		right, err := p.parseNear()
+		if err != nil {
+			return nil, err
+		}
+		node = &queryNode{op: opAnd, children: []*queryNode{node, right}}
+	}
+}
+
+func (p *queryParser) parseNear() (*queryNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		distance, ok := nearDistance(p.peek())
+		if !ok {
+			return node, nil
+		}
+		p.next++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if node.op != opTerm || right.op != opTerm {
+			return nil, errors.New("NEAR only works between words or phrases")
+		}
+		node = &queryNode{op: opNear, children: []*queryNode{node, right}, distance: distance}
+	}
+}
+
+func (p *queryParser) parseUnary() (*queryNode, error) {
+	if p.atEnd() {
+		return nil, errors.New("the query ends too soon")
+	}
+	token := p.tokens[p.next]
+	p.next++
+	if !token.isOperator() {
+		return &queryNode{op: opTerm, term: token.text}, nil
+	}
+	switch token.text {
+	case opNot:
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNode{op: opNot, children: []*queryNode{child}}, nil
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.next++
+		return node, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", token.text)
+}
+
+// the terms a matching note contains, as opposed to ones it mustn't
+func (n *queryNode) positiveTerms() []string {
+	switch n.op {
+	case opTerm:
+		return []string{n.term}
+	case opNot:
+		return nil
+	}
+	var terms []string
+	for _, child := range n.children {
+		terms = append(terms, child.positiveTerms()...)
+	}
+	return terms
+}
+
+func (n *queryNode) hasNot() bool {
+	if n.op == opNot {
+		return true
+	}
+	for _, child := range n.children {
+		if child.hasNot() {
+			return true
+		}
+	}
+	return false
+}
+
+// what evaluating a query needs to know about the files being searched
+type queryEnv struct {
+	// the files containing term
+	filesWith func(term string) map[string]bool
+	// every file, which only NOT needs
+	allFiles map[string]bool
+	// whether file has a and b within distance words of each other
+	near func(file string, a string, b string, distance int) bool
+}
+
+// evaluate works out which files satisfy the query
+func (n *queryNode) evaluate(env queryEnv) map[string]bool {
+	switch n.op {
+	case opTerm:
+		return env.filesWith(n.term)
+	case opNot:
+		excluded := n.children[0].evaluate(env)
+		files := make(map[string]bool)
+		for file := range env.allFiles {
+			if !excluded[file] {
+				files[file] = true
+			}
+		}
+		return files
+	case opAnd, opNear:
+		left := n.children[0].evaluate(env)
+		right := n.children[1].evaluate(env)
+		files := make(map[string]bool)
+		for file := range left {
+			if !right[file] {
+				continue
+			}
+			if n.op == opAnd || env.near(file, n.children[0].term, n.children[1].term, n.distance) {
+				files[file] = true
+			}
+		}
+		return files
+	}
+	files := make(map[string]bool)
+	for _, child := range n.children {
+		for file := range child.evaluate(env) {
+			files[file] = true
+		}
+	}
+	return files
+}
@@ -0,0 +1,110 @@
+package osearch
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// a query tree written out as OP(children...), terms as they are
+func describeQuery(n *queryNode) string {
+	if n.op == opTerm {
+		return n.term
+	}
+	children := make([]string, len(n.children))
+	for index, child := range n.children {
+		children[index] = describeQuery(child)
+	}
+	op := n.op
+	if n.op == opNear {
+		op += "/" + strconv.Itoa(n.distance)
+	}
+	return op + "(" + strings.Join(children, ", ") + ")"
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"budget", "budget"},
+		{"budget forecast", "AND(budget, forecast)"},
+		{"budget AND (2024 OR 2025) NOT draft", "AND(AND(budget, OR(2024, 2025)), NOT(draft))"},
+		{"a OR b c", "OR(a, AND(b, c))"},
+		{`"annual budget" NEAR/5 forecast`, "NEAR/5(annual budget, forecast)"},
+		{`"AND" or`, "AND(AND, or)"},
+	}
+	for _, test := range tests {
+		node, err := parseQuery(test.query)
+		if err != nil {
+			t.Errorf("parseQuery(%q): %s", test.query, err)
+			continue
+		}
+		if got := describeQuery(node); got != test.want {
+			t.Errorf("parseQuery(%q) = %s, want %s", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{"", "(draft", "budget AND", "a )", "a NEAR/2 (b OR c)"} {
+		if node, err := parseQuery(query); err == nil {
+			t.Errorf("parseQuery(%q) = %s, want an error", query, describeQuery(node))
+		}
+	}
+}
+
+func TestPositiveTerms(t *testing.T) {
+	node, err := parseQuery("budget (2024 OR 2025) NOT draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"budget", "2024", "2025"}
+	if got := node.positiveTerms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("positiveTerms() = %q, want %q", got, want)
+	}
+	if !node.hasNot() {
+		t.Error("hasNot() = false, want true")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	files := map[string]string{
+		"a.md": "budget 2024",
+		"b.md": "budget 2025 draft",
+		"c.md": "forecast 2024",
+	}
+	all := make(map[string]bool)
+	for file := range files {
+		all[file] = true
+	}
+	env := queryEnv{
+		filesWith: func(term string) map[string]bool {
+			with := make(map[string]bool)
+			for file, text := range files {
+				if strings.Contains(text, term) {
+					with[file] = true
+				}
+			}
+			return with
+		},
+		allFiles: all,
+	}
+
+	node, err := parseQuery("budget (2024 OR 2025) NOT draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"a.md": true}
+	if got := node.evaluate(env); !reflect.DeepEqual(got, want) {
+		t.Errorf("evaluate() = %v, want %v", got, want)
+	}
+}
+
+func TestQueryWords(t *testing.T) {
+	want := []string{"meeting", "annual budget", "(", "q3", ")"}
+	if got := queryWords(`meeting "annual budget" (q3)`); !reflect.DeepEqual(got, want) {
+		t.Errorf("queryWords() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package osearch
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// how much each kind of match counts towards a note's score. Matches are
+// added up, then boosted for notes edited recently: a note edited today gets
+// up to Recency times its score again, which halves every HalfLifeDays.
+// Notes opened from results before get Frecency for each recent visit.
+type RankingWeights struct {
+	Title        float64 `json:"title"`
+	Heading      float64 `json:"heading"`
+	Body         float64 `json:"body"`
+	Recency      float64 `json:"recency"`
+	HalfLifeDays float64 `json:"halfLifeDays"`
+	Frecency     float64 `json:"frecency"`
+}
+
+var DefaultRankingWeights = RankingWeights{
+	Title:        10,
+	Heading:      3,
+	Body:         1,
+	Recency:      1,
+	HalfLifeDays: 30,
+	Frecency:     2,
+}
+
+// everything a content search found in one file
+type fileMatches struct {
+	filename       string
+	lines          []matchedLine
+	headingMatches int
+	bodyMatches    int
+	score          float64
+}
+
+type matchedLine struct {
+	text   string
+	number int
+	// byte offsets in text of the first match rg found
+	start int
+	end   int
+}
+
+func (m *fileMatches) count() int {
+	return m.headingMatches + m.bodyMatches
+}
+
+func isHeading(line string) bool {
+	return headingPattern.MatchString(strings.TrimSpace(line))
+}
+
+// whether the note's title contains one of the terms searched for
+func titleMatches(filename string, terms []string, caseMode string) bool {
+	title := foldDiacritics(toNFC(withoutMd(filepath.Base(filename))))
+	for _, term := range terms {
+		folded := foldDiacritics(term)
+		if isCaseSensitive(term, caseMode) {
+			if strings.Contains(title, folded) {
+				return true
+			}
+		} else if strings.Contains(strings.ToLower(title), strings.ToLower(folded)) {
+			return true
+		}
+	}
+	return false
+}
+
+func scoreMatches(m *fileMatches, terms []string, vault string, config Config, visits Visits, now time.Time) float64 {
+	weights := config.Ranking
+	score := 0.0
+	if titleMatches(m.filename, terms, config.Case) {
+		score += weights.Title
+	}
+	// repeated mentions help, but with diminishing returns
+	score += weights.Heading * math.Log1p(float64(m.headingMatches))
+	score += weights.Body * math.Log1p(float64(m.bodyMatches))
+
+	if info, err := os.Stat(config.vaultPath(m.filename)); err == nil && weights.HalfLifeDays > 0 {
+		ageDays := now.Sub(info.ModTime()).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		score *= 1 + weights.Recency*math.Pow(0.5, ageDays/weights.HalfLifeDays)
+	}
+	score += weights.Frecency * visits.frecency(resultUid(m.filename, vault), now)
+	return score
+}
+
+// which of a note's matching lines --grep shows, and in what order
+const (
+	// the first lines of the note that match, up to --per-file
+	DedupeFirst = "first"
+	// the lines matching best, up to --per-file
+	DedupeBest = "best"
+	// every line that matches
+	DedupeOff = "off"
+)
+
+// the lines of m to show, as config.Dedupe says, before cutting them down
+// to config.PerFile. Ranking lines best first counts how many different
+// terms each has, weighting headings over body text the way notes are
+// ranked, and keeps them in order otherwise.
+func shownLines(m *fileMatches, terms []string, config Config) []matchedLine {
+	switch config.Dedupe {
+	case DedupeOff:
+		return m.lines
+	case DedupeBest:
+	default:
+		if len(m.lines) > config.PerFile {
+			return m.lines[:config.PerFile]
+		}
+		return m.lines
+	}
+
+	var patterns []*regexp.Regexp
+	for _, term := range terms {
+		if pattern, err := regexp.Compile(termPattern(term, config)); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+	scores := make([]float64, len(m.lines))
+	for index, line := range m.lines {
+		weight := config.Ranking.Body
+		if isHeading(line.text) {
+			weight = config.Ranking.Heading
+		}
+		found := 0
+		for _, pattern := range patterns {
+			if pattern.MatchString(line.text) {
+				found++
+			}
+		}
+		scores[index] = weight * float64(1+found)
+	}
+	order := make([]int, len(m.lines))
+	for index := range order {
+		order[index] = index
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if len(order) > config.PerFile {
+		order = order[:config.PerFile]
+	}
+	lines := make([]matchedLine, len(order))
+	for index, line := range order {
+		lines[index] = m.lines[line]
+	}
+	return lines
+}
+
+// order files best first, falling back on the number of matches and then
+// the name, since rg finds them in no particular order
+func rankMatches(matches []*fileMatches, terms []string, vault string, config Config, visits Visits) {
+	now := time.Now()
+	for _, m := range matches {
+		m.score = scoreMatches(m, terms, vault, config, visits, now)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].count() != matches[j].count() {
+			return matches[i].count() > matches[j].count()
+		}
+		return matches[i].filename < matches[j].filename
+	})
+}
@@ -0,0 +1,59 @@
+package osearch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankMatches(t *testing.T) {
+	matches := []*fileMatches{
+		{filename: "b.md", bodyMatches: 1},
+		{filename: "Notes/Budget.md", bodyMatches: 1},
+		{filename: "a.md", bodyMatches: 1},
+		{filename: "c.md", headingMatches: 1},
+		{filename: "d.md", bodyMatches: 4},
+	}
+	config := Config{Ranking: DefaultRankingWeights, directory: t.TempDir()}
+	rankMatches(matches, []string{"budget"}, "vault", config, Visits{})
+
+	want := []string{"Notes/Budget.md", "c.md", "d.md", "a.md", "b.md"}
+	for index, m := range matches {
+		if m.filename != want[index] {
+			t.Fatalf("ranked %s at %d, want %s", m.filename, index, want[index])
+		}
+	}
+}
+
+func TestRankMatchesVisits(t *testing.T) {
+	matches := []*fileMatches{
+		{filename: "a.md", bodyMatches: 2},
+		{filename: "b.md", bodyMatches: 1},
+	}
+	visits := Visits{}
+	visits.record(resultUid("b.md", "vault"), time.Now())
+	config := Config{Ranking: DefaultRankingWeights, directory: t.TempDir()}
+	rankMatches(matches, []string{"budget"}, "vault", config, visits)
+
+	if matches[0].filename != "b.md" {
+		t.Errorf("ranked %s first, want the visited b.md", matches[0].filename)
+	}
+}
+
+func TestTitleMatches(t *testing.T) {
+	tests := []struct {
+		filename string
+		term     string
+		want     bool
+	}{
+		{"Work/Budget 2024.md", "budget", true},
+		{"Work/Budget 2024.md", "Budget", true},
+		{"Work/budget 2024.md", "Budget", false},
+		{"Work/Café.md", "cafe", true},
+		{"Budget/Notes.md", "budget", false},
+	}
+	for _, test := range tests {
+		if got := titleMatches(test.filename, []string{test.term}, SmartCase); got != test.want {
+			t.Errorf("titleMatches(%q, %q) = %v, want %v", test.filename, test.term, got, test.want)
+		}
+	}
+}
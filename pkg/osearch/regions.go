@@ -0,0 +1,77 @@
+package osearch
+
+import (
+	"strings"
+)
+
+// which lines of a note, numbered from 1 as rg numbers them, are its
+// frontmatter and which are in fenced code blocks
+type noteRegions struct {
+	// the line closing the frontmatter, or 0 if there isn't any
+	frontmatterEnd int
+	code           map[int]bool
+}
+
+func readRegions(filename string) noteRegions {
+	regions := noteRegions{code: make(map[int]bool)}
+	content, err := readNote(filename)
+	if err != nil {
+		return regions
+	}
+
+	lines := strings.Split(strings.TrimPrefix(string(content), "\ufeff"), "\n")
+	start := 0
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r") == "---" {
+		for index := 1; index < len(lines); index++ {
+			line := strings.TrimRight(lines[index], "\r")
+			if line == "---" || line == "..." {
+				regions.frontmatterEnd = index + 1
+				start = index + 1
+				break
+			}
+		}
+	}
+
+	// a fence is closed by a run of the same character at least as long
+	fence := ""
+	for index := start; index < len(lines); index++ {
+		trimmed := strings.TrimSpace(lines[index])
+		if len(fence) == 0 {
+			if marker := fenceMarker(trimmed); len(marker) > 0 {
+				fence = marker
+				regions.code[index+1] = true
+			}
+			continue
+		}
+		regions.code[index+1] = true
+		if marker := fenceMarker(trimmed); len(marker) >= len(fence) && marker[0] == fence[0] && len(trimmed) == len(marker) {
+			fence = ""
+		}
+	}
+	return regions
+}
+
+// the run of backticks or tildes opening a fenced code block, if line starts one
+func fenceMarker(line string) string {
+	for _, char := range []byte{'`', '~'} {
+		length := 0
+		for length < len(line) && line[length] == char {
+			length++
+		}
+		if length >= 3 {
+			return line[:length]
+		}
+	}
+	return ""
+}
+
+// whether config leaves matches on this line out of content search
+func (regions noteRegions) excludes(number int, config Config) bool {
+	if config.Frontmatter && (number <= 1 || number >= regions.frontmatterEnd) {
+		return true
+	}
+	if config.NoFrontmatter && number <= regions.frontmatterEnd {
+		return true
+	}
+	return config.NoCode && regions.code[number]
+}
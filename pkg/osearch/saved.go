@@ -0,0 +1,46 @@
+package osearch
+
+import (
+	"sort"
+	"strings"
+)
+
+// the saved search named by what was typed, if there is one
+func savedSearch(name string, searches map[string]string) (string, bool) {
+	name = strings.TrimSpace(name)
+	for saved, query := range searches {
+		if strings.EqualFold(saved, name) {
+			return query, true
+		}
+	}
+	return "", false
+}
+
+// the saved searches whose names have what was typed in them, by name, as
+// items that fill the name in so Alfred runs them
+func savedSearchResults(typed string, searches map[string]string) AlfredResults {
+	results := AlfredResults{Items: []AlfredResult{}}
+	typed = strings.TrimSpace(typed)
+	var names []string
+	for name := range searches {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(typed)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		valid := false
+		results.Items = append(results.Items, AlfredResult{
+			Type:         "default",
+			Valid:        &valid,
+			UID:          "saved:" + name,
+			Title:        name,
+			Subtitle:     searches[name],
+			Autocomplete: name,
+		})
+	}
+	if len(results.Items) == 0 {
+		results.Items = append(results.Items, errorResult("No saved search called "+typed, `Add one to "searches" in the config`))
+	}
+	return results
+}
@@ -0,0 +1,240 @@
+package osearch
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the ways of searching a vault
+const (
+	// match file names, falling back to contents if config allows
+	ModeName = "name"
+	// match file names fuzzily
+	ModeFuzzy = "fuzzy"
+	// match contents
+	ModeGrep = "grep"
+	// match frontmatter only
+	ModeFrontmatter = "frontmatter"
+	// match file names and contents together
+	ModeBoth = "both"
+	// list every note for Alfred to filter
+	ModeList = "list"
+	// find notes close in meaning, by their embeddings
+	ModeSemantic = "semantic"
+	// match the names of templates in the templates plugin's folder
+	ModeTemplates = "templates"
+	// match the names of the copies sync tools make of conflicting edits
+	ModeConflicts = "conflicts"
+)
+
+// Options says what to search for, where, and what to do with the results
+type Options struct {
+	Mode  string
+	Query string
+	// the vault's name, as Obsidian URLs need it, and its folder
+	Vault string
+	Path  string
+	// only search this folder of the vault
+	InFolder string
+	Config   Config
+	// zero times leave that end open
+	ModifiedSince  time.Time
+	ModifiedBefore time.Time
+	CreatedSince   time.Time
+	CreatedBefore  time.Time
+	Sort           string
+	GroupBy        string
+	// auto, true or false
+	SkipKnowledge string
+	// how long Alfred may cache the results; negative picks a default for
+	// the mode
+	CacheSeconds int
+	RerunSeconds float64
+	PreviewHtml  bool
+	// the query is the name of one of the config's saved searches
+	Saved bool
+	// Found, if set, is given each result as soon as the search has it,
+	// unranked, and Search only returns what it couldn't hand over that way:
+	// messages, and the results of modes that only have them all at once
+	Found func(AlfredResult) error
+}
+
+// Search runs a search of a vault and returns its results ready to write out
+func Search(options Options) (AlfredResults, error) {
+	start := time.Now()
+	config := options.Config
+	config.Frontmatter = options.Mode == ModeFrontmatter
+	backend, err := backendFor(options.Mode, config)
+	if err != nil {
+		return AlfredResults{}, err
+	}
+	config.backend = backend
+	Debug("search", "mode", options.Mode, "query", options.Query, "vault", options.Vault, "path", options.Path, "backend", backendName(options.Mode, config))
+	directory := ExpandHome(options.Path)
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return AlfredResults{}, exitError(ExitVault, "no such directory %s", directory)
+	}
+	config.directory = directory
+	if !isCaseMode(config.Case) {
+		return AlfredResults{}, exitError(ExitConfig, "unknown case mode %s", config.Case)
+	}
+	vault := options.Vault
+	// typed or pasted in decomposed form, the query would miss composed
+	// text; foldingPattern takes care of decomposed file names
+	searchTerm := toNFC(options.Query)
+	cacheSeconds := options.CacheSeconds
+
+	if options.Saved {
+		query, ok := savedSearch(searchTerm, config.Searches)
+		if !ok {
+			return savedSearchResults(searchTerm, config.Searches), nil
+		}
+		searchTerm = query
+	} else if config.History > 0 && options.Mode != ModeList {
+		if len(strings.TrimSpace(searchTerm)) == 0 {
+			return historyResults(loadHistory()[options.Mode]), nil
+		}
+		recordSearch(options.Mode, searchTerm, config)
+	}
+
+	// file:, tag: and path: narrow down whatever the rest of the query finds
+	var fields fieldFilters
+	if !config.Regex {
+		fields, searchTerm = extractFields(searchTerm)
+	}
+	if len(options.InFolder) > 0 {
+		if !hasFolder(directory, options.InFolder) {
+			// a keyword set up for a folder that's since been renamed would
+			// otherwise just never find anything
+			return AlfredResults{Items: []AlfredResult{errorResult("No folder "+strings.Trim(options.InFolder, "/"), "in "+directory)}}, nil
+		}
+		fields.paths = append(fields.paths, strings.Trim(options.InFolder, "/"))
+	}
+	config.Folders = searchRoots(fields.paths, directory)
+	sortOrder := options.Sort
+	// sort:mod is most likely sort:modified half typed, so anything that
+	// isn't an order yet is left alone rather than failing every keystroke
+	if isSortOrder(fields.sort) {
+		sortOrder = fields.sort
+	}
+	if len(options.GroupBy) > 0 && options.GroupBy != "folder" {
+		return AlfredResults{}, exitError(ExitUsage, "can't group results by %s", options.GroupBy)
+	}
+	var skipKnowledge bool
+	switch options.SkipKnowledge {
+	case "auto", "":
+		// Alfred reordering results would scatter the groups or undo the
+		// order asked for; only relevance is fair game
+		skipKnowledge = len(options.GroupBy) > 0 || (len(sortOrder) > 0 && sortOrder != SortRelevance)
+	case "true", "false":
+		skipKnowledge = options.SkipKnowledge == "true"
+	default:
+		return AlfredResults{}, exitError(ExitUsage, "--skip-knowledge must be auto, true or false")
+	}
+
+	// what each result goes through to be shown, whether it's streamed as
+	// soon as it's found or waits for the rest to be ranked
+	show := func(items []AlfredResult) []AlfredResult {
+		items = withFields(items, fields, config.Case)
+		items = modifiedBetween(items, options.ModifiedSince, options.ModifiedBefore)
+		items = createdBetween(items, options.CreatedSince, options.CreatedBefore)
+		items = withoutIgnored(items, config.Ignore)
+		if options.Mode != ModeTemplates && !config.IncludeTemplates {
+			items = withoutTemplates(items, directory, fields.paths)
+		}
+		if options.Mode != ModeConflicts && !config.IncludeConflicts {
+			items = withoutConflicts(items)
+		}
+		if !config.NestedVaults {
+			items = withoutNestedVaults(items, directory)
+		}
+		if config.ExcludeAttachments {
+			if folder := sharedAttachmentFolder(directory); len(folder) > 0 {
+				items = withoutIgnored(items, []string{folder})
+			}
+		}
+		// only what's shown, so an empty search doesn't bring back the vault
+		if config.ICloudDownload && len(strings.TrimSpace(searchTerm)) > 0 {
+			downloadEvicted(items)
+		}
+		if key, ok := actionMods[config.Action]; ok {
+			withMainAction(items, key)
+		}
+		if config.WordCount {
+			addWordCounts(AlfredResults{Items: items})
+		}
+		if config.ShowVault {
+			addVaultNames(AlfredResults{Items: items}, directory)
+		}
+		if options.PreviewHtml {
+			addHtmlPreviews(AlfredResults{Items: items}, directory, vault)
+		}
+		return items
+	}
+	streamed := 0
+	var streamErr error
+	if options.Found != nil {
+		config.found = func(result AlfredResult) {
+			streamed++
+			for _, shown := range show([]AlfredResult{result}) {
+				if streamErr == nil {
+					streamErr = options.Found(shown)
+				}
+			}
+		}
+	}
+
+	var results AlfredResults
+	if _, compileErr := regexp.Compile(searchTerm); config.Regex && compileErr != nil {
+		results.Items = []AlfredResult{errorResult("Invalid regular expression", compileErr.Error())}
+	} else if options.Mode == ModeList {
+		results, err = listAllNotes(directory, vault, config)
+		if cacheSeconds < 0 {
+			cacheSeconds = ListCacheSeconds
+		}
+	} else if (options.Mode == ModeGrep || options.Mode == ModeFrontmatter) && len(searchTerm) > 0 {
+		results, err = grepMatchingFiles(searchTerm, directory, vault, config)
+	} else if options.Mode == ModeSemantic && len(searchTerm) > 0 {
+		results, err = semanticMatchingFiles(searchTerm, directory, vault, config)
+	} else if options.Mode == ModeTemplates {
+		results, err = templateResults(searchTerm, directory, vault, config)
+	} else if options.Mode == ModeConflicts {
+		results, err = conflictResults(searchTerm, directory, vault, config)
+	} else if options.Mode == ModeBoth {
+		results, err = bothMatchingFiles(searchTerm, directory, vault, config)
+	} else if options.Mode == ModeFuzzy {
+		results, err = fuzzyMatchingFiles(searchTerm, directory, vault, config)
+	} else {
+		results, err = findMatchingFiles(searchTerm, directory, vault, config)
+		if err == nil && len(results.Items) == 0 && len(searchTerm) > 0 && config.Fallback {
+			results, err = contentFallback(searchTerm, directory, vault, config)
+		}
+	}
+	if err != nil {
+		return results, err
+	}
+	if streamed > 0 {
+		// each has been handed over already, unranked
+		Debug("results", "streamed", streamed, "took", time.Since(start))
+		return AlfredResults{}, streamErr
+	}
+
+	err = sortResults(results.Items, sortOrder)
+	if err != nil {
+		return results, err
+	}
+	results.Items = show(results.Items)
+	if options.Mode != ModeList {
+		results.Items = pinFirst(results.Items, config.Pinned)
+	}
+	if options.GroupBy == "folder" {
+		results = groupByFolder(results, vault)
+	}
+	results.SkipKnowledge = skipKnowledge
+	results.Cache = cacheFor(cacheSeconds)
+	results.Rerun = rerunAfter(options.RerunSeconds)
+	Debug("results", "count", len(results.Items), "took", time.Since(start))
+	return results, nil
+}
@@ -0,0 +1,236 @@
+package osearch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// a vault of notes with the given text, and a data folder for osearch to
+// keep its files in while the test runs
+func testVault(t *testing.T, notes map[string]string) string {
+	directory := t.TempDir()
+	for name, text := range notes {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := os.Getenv("alfred_workflow_data")
+	os.Setenv("alfred_workflow_data", t.TempDir())
+	t.Cleanup(func() { os.Setenv("alfred_workflow_data", data) })
+	return directory
+}
+
+func TestSearch(t *testing.T) {
+	directory := testVault(t, map[string]string{
+		"Fruit.md":           "# Fruit\napples and pears\n",
+		"Work/Budget.md":     "the budget for apples\n",
+		"Work/Meeting.md":    "nothing to see\n",
+		".obsidian/fruit.md": "hidden apples\n",
+	})
+	config, err := LoadConfig(filepath.Join(directory, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.PerFile = 1
+	// notes written a moment apart would otherwise rank by which was last
+	config.Ranking.Recency = 0
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode    string
+		query   string
+		backend string
+		want    []string
+	}{
+		{ModeName, "fruit", BackendNative, []string{"Fruit.md"}},
+		{ModeName, "budget", BackendIndex, []string{"Work/Budget.md"}},
+		{ModeGrep, "apples", BackendNative, []string{"Fruit.md", "Work/Budget.md"}},
+		{ModeGrep, "apples NOT budget", BackendIndex, []string{"Fruit.md"}},
+		{ModeGrep, "path:Work apples", BackendNative, []string{"Work/Budget.md"}},
+	}
+	for _, test := range tests {
+		config.Backend = test.backend
+		results, err := Search(Options{Mode: test.mode, Query: test.query, Vault: "vault", Path: directory, Config: config, CacheSeconds: -1})
+		if err != nil {
+			t.Errorf("%s %q: %s", test.mode, test.query, err)
+			continue
+		}
+		var got []string
+		for _, result := range results.Items {
+			got = append(got, result.Variables["path"])
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s %q found %q, want %q", test.mode, test.query, got, test.want)
+			continue
+		}
+		for index := range got {
+			if got[index] != test.want[index] {
+				t.Errorf("%s %q found %q, want %q", test.mode, test.query, got, test.want)
+				break
+			}
+		}
+	}
+
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("the search moved the working directory to %s", now)
+	}
+}
+
+func TestSearchMissingVault(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "gone")
+	_, err := Search(Options{Mode: ModeName, Query: "note", Path: directory, Config: Config{Backend: BackendNative}})
+	if err == nil {
+		t.Fatal("searching a missing vault didn't fail")
+	}
+	if code := ExitCodeFor(err); code != ExitVault {
+		t.Errorf("exit code %d, want %d", code, ExitVault)
+	}
+}
+
+func TestSearchBadConfig(t *testing.T) {
+	directory := testVault(t, map[string]string{"Note.md": "text\n"})
+	tests := []struct {
+		config Config
+		want   ExitCode
+	}{
+		{Config{Backend: "grep"}, ExitBackend},
+		{Config{Backend: BackendNative, Regex: true, Case: "loud"}, ExitConfig},
+	}
+	for _, test := range tests {
+		_, err := Search(Options{Mode: ModeName, Query: "note", Path: directory, Config: test.config})
+		if code := ExitCodeFor(err); code != test.want {
+			t.Errorf("searching with %+v: exit code %d (%v), want %d", test.config, code, err, test.want)
+		}
+	}
+	_, err := Search(Options{Mode: ModeName, Query: "note", Path: directory, GroupBy: "tag", Config: Config{Backend: BackendNative}})
+	if code := ExitCodeFor(err); code != ExitUsage {
+		t.Errorf("grouping by tag: exit code %d (%v), want %d", code, err, ExitUsage)
+	}
+}
+
+func TestSearchICloud(t *testing.T) {
+	vault := filepath.Join("Library", "Mobile Documents", "Vault")
+	directory := filepath.Join(testVault(t, map[string]string{
+		filepath.Join(vault, "Here.md"):                     "# Here\n",
+		filepath.Join(vault, ".Away.md.icloud"):             "",
+		filepath.Join(vault, "Work", ".Far Away.md.icloud"): "",
+		filepath.Join(vault, ".trash", ".Binned.md.icloud"): "",
+		filepath.Join(vault, ".away notes.md"):              "",
+	}), vault)
+	config, err := LoadConfig(filepath.Join(directory, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Fallback = false
+
+	for _, backend := range []string{BackendNative, BackendIndex} {
+		config.Backend = backend
+		results, err := Search(Options{Mode: ModeName, Query: "away", Vault: "vault", Path: directory, Config: config, CacheSeconds: -1})
+		if err != nil {
+			t.Fatalf("%s: %s", backend, err)
+		}
+		found := make(map[string]string)
+		for _, result := range results.Items {
+			found[result.Variables["path"]] = result.Subtitle
+		}
+		if len(found) != 2 || found["Away.md"] == "" || found["Work/Far Away.md"] == "" {
+			t.Errorf("%s found %q, want Away.md and Work/Far Away.md", backend, found)
+		}
+		for path, subtitle := range found {
+			if !strings.Contains(subtitle, "not downloaded") {
+				t.Errorf("%s: %s is subtitled %q", backend, path, subtitle)
+			}
+		}
+	}
+}
+
+func TestSearchVaults(t *testing.T) {
+	first := testVault(t, map[string]string{"Work/Plan.md": "plan\n", "Plan B.md": "plan\n"})
+	second := testVault(t, map[string]string{"Work/Plan C.md": "plan\n", "Home/Plan D.md": "plan\n"})
+	vaults := []VaultLocation{{Name: "first", Path: first}, {Name: "second", Path: second}}
+	config := Config{Backend: BackendNative, Ranking: DefaultRankingWeights, PerFile: 1}
+
+	regex := config
+	regex.Regex = true
+	results, err := SearchVaults(Options{Mode: ModeName, Query: "(", Config: regex}, vaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 1 {
+		t.Errorf("an invalid regex gave %d items, want 1 for all the vaults", len(results.Items))
+	}
+
+	results, err = SearchVaults(Options{Mode: ModeName, Query: "plan", Sort: SortTitle, GroupBy: "folder", Config: config}, vaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each folder's header is followed by its notes
+	folder := ""
+	for _, result := range results.Items {
+		path := result.Variables["path"]
+		if len(path) == 0 {
+			folder = result.Title
+			continue
+		}
+		want := filepath.Dir(path)
+		if want == "." {
+			want = result.Variables["vault"]
+		}
+		if folder != want {
+			t.Errorf("%s is under the header %q", path, folder)
+		}
+	}
+}
+
+func TestSearchStreams(t *testing.T) {
+	directory := testVault(t, map[string]string{
+		"Fruit.md":       "apples\n",
+		"Work/Budget.md": "apples for work\n",
+		"Home.md":        "nothing\n",
+	})
+	config := Config{Backend: BackendNative, Ranking: DefaultRankingWeights, PerFile: 1, Ignore: []string{"Work"}}
+	tests := []struct {
+		mode  string
+		query string
+		want  int
+	}{
+		{ModeName, "", 2},
+		{ModeGrep, "apples", 1},
+	}
+	for _, test := range tests {
+		var found []string
+		options := Options{Mode: test.mode, Query: test.query, Path: directory, Config: config, CacheSeconds: -1}
+		options.Found = func(result AlfredResult) error {
+			found = append(found, result.Variables["path"])
+			return nil
+		}
+		results, err := Search(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results.Items) != 0 || len(found) != test.want {
+			t.Errorf("%s %q streamed %q and returned %d, want %d streamed", test.mode, test.query, found, len(results.Items), test.want)
+		}
+	}
+
+	// a message isn't found, so it's returned as usual
+	options := Options{Mode: ModeGrep, Query: "(apples", Path: directory, Config: config}
+	options.Found = func(result AlfredResult) error {
+		t.Errorf("streamed %q", result.Title)
+		return nil
+	}
+	results, err := Search(options)
+	if err != nil || len(results.Items) != 1 {
+		t.Errorf("a half-typed query returned %d items (%v), want its message", len(results.Items), err)
+	}
+}
@@ -0,0 +1,232 @@
+package osearch
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// how many of the closest notes a semantic search shows
+const semanticResults = 20
+
+// how much of a note goes into its embedding; models only look at the
+// start anyway
+const maxEmbeddedText = 8000
+
+// how many notes are embedded between saves, so stopping halfway through
+// a vault keeps most of the work
+const embeddingsSaveEvery = 25
+
+// what the embedding command said about each note, kept until the note
+// changes
+type embeddingCache struct {
+	Notes map[string]cachedEmbedding
+}
+
+type cachedEmbedding struct {
+	ModTime int64
+	Size    int64
+	Vector  []float64
+}
+
+// the notes closest in meaning to the query, by the cosine similarity of
+// their embeddings to its. Only the query is embedded here: the notes'
+// embeddings are worked out by osearch index, and a note edited since is
+// compared by the one it had.
+func semanticMatchingFiles(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	if len(config.EmbedCommand) == 0 {
+		return AlfredResults{Items: []AlfredResult{errorResult("Semantic search needs an embedding command", `Set "embedCommand" in the config file`)}}, nil
+	}
+	query, err := embed(searchTerm, config)
+	if err != nil {
+		return AlfredResults{Items: []AlfredResult{errorResult("Could not embed the search", err.Error())}}, nil
+	}
+	files, err := config.searchBackend().FindFiles("", config)
+	if err != nil {
+		return AlfredResults{}, err
+	}
+
+	cache := loadEmbeddings(directory, config)
+	similarity := make(map[string]float64)
+	var notes []string
+	missing := 0
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".md") {
+			continue
+		}
+		cached, ok := cache.Notes[file]
+		if !ok {
+			missing++
+			continue
+		}
+		similarity[file] = cosineSimilarity(query, cached.Vector)
+		notes = append(notes, file)
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return similarity[notes[i]] > similarity[notes[j]]
+	})
+	if len(notes) > semanticResults {
+		notes = notes[:semanticResults]
+	}
+	var results []AlfredResult
+	for _, note := range notes {
+		result := noteResult(note, directory, vault, config)
+		result.Subtitle = fmt.Sprintf("%.0f%% similar · %s", 100*similarity[note], result.Subtitle)
+		results = append(results, result)
+	}
+	if missing > 0 {
+		results = append(results, errorResult(plural(missing, "note")+" not embedded yet", "Run osearch index, or install the service, to embed them"))
+	}
+	return AlfredResults{Items: results}, nil
+}
+
+// UpdateEmbeddings works out the embedding of each note in the vault in
+// directory that's new or changed since it was last embedded, and forgets
+// the notes that are gone, so semantic searches only have to embed what's
+// typed. Progress is saved as it goes.
+func UpdateEmbeddings(directory string, config Config) error {
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return exitError(ExitVault, "no such directory %s", directory)
+	}
+	config.directory = directory
+	config.Folders = nil
+	cache := loadEmbeddings(directory, config)
+	files := make(map[string]bool)
+	for _, file := range walkVault(config) {
+		if strings.HasSuffix(file, ".md") {
+			files[file] = true
+		}
+	}
+	unsaved := 0
+	for file := range cache.Notes {
+		if !files[file] {
+			delete(cache.Notes, file)
+			unsaved++
+		}
+	}
+	for file := range files {
+		info, err := os.Stat(config.vaultPath(file))
+		if err != nil {
+			continue
+		}
+		cached, ok := cache.Notes[file]
+		if ok && cached.ModTime == info.ModTime().UnixNano() && cached.Size == info.Size() {
+			continue
+		}
+		content, err := readNote(config.vaultPath(file))
+		if err != nil {
+			continue
+		}
+		_, body := parseFrontmatter(string(content))
+		text := withoutMd(filepath.Base(file)) + "\n" + body
+		if len(text) > maxEmbeddedText {
+			text = strings.ToValidUTF8(text[:maxEmbeddedText], "")
+		}
+		vector, err := embed(text, config)
+		if err != nil {
+			log.Printf("could not embed %s: %s", file, err)
+			continue
+		}
+		cache.Notes[file] = cachedEmbedding{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Vector: vector}
+		unsaved++
+		if unsaved >= embeddingsSaveEvery {
+			if err := saveEmbeddings(directory, config, cache); err != nil {
+				return err
+			}
+			unsaved = 0
+		}
+	}
+	if unsaved == 0 {
+		return nil
+	}
+	return saveEmbeddings(directory, config, cache)
+}
+
+// run the embedding command with text on its standard input; it answers
+// with a JSON list of numbers
+func embed(text string, config Config) ([]float64, error) {
+	command := exec.Command(config.EmbedCommand[0], config.EmbedCommand[1:]...)
+	command.Stdin = strings.NewReader(text)
+	out, err := commandOutput(command)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", strings.Join(config.EmbedCommand, " "), err)
+	}
+	var vector []float64
+	err = json.Unmarshal(out, &vector)
+	if err != nil {
+		return nil, fmt.Errorf("%s didn't print a list of numbers: %s", strings.Join(config.EmbedCommand, " "), err)
+	}
+	return vector, nil
+}
+
+func cosineSimilarity(a []float64, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// embeddings are kept per vault and per command, since another model's
+// vectors can't be compared with these
+func embeddingsFile(directory string, config Config) string {
+	command := sha1.Sum([]byte(strings.Join(config.EmbedCommand, "\x00")))
+	return vaultDataFile(directory, "embeddings", fmt.Sprintf("%x.gob", command))
+}
+
+func loadEmbeddings(directory string, config Config) embeddingCache {
+	cache := embeddingCache{Notes: make(map[string]cachedEmbedding)}
+	file, err := os.Open(embeddingsFile(directory, config))
+	if err != nil {
+		return cache
+	}
+	defer file.Close()
+	if gob.NewDecoder(file).Decode(&cache) != nil {
+		return embeddingCache{Notes: make(map[string]cachedEmbedding)}
+	}
+	return cache
+}
+
+// write cache to a file of its own and swap it in, so a search never reads
+// half of one and two updates at once don't mix theirs
+func saveEmbeddings(directory string, config Config, cache embeddingCache) error {
+	filename := embeddingsFile(directory, config)
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return fmt.Errorf("could not save embeddings: %w", err)
+	}
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not save embeddings: %w", err)
+	}
+	err = gob.NewEncoder(file).Encode(cache)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("could not save embeddings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package osearch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateEmbeddings(t *testing.T) {
+	directory := testVault(t, map[string]string{"Kept.md": "kept\n", "Gone.md": "gone\n"})
+	config := Config{Backend: BackendNative, EmbedCommand: []string{"sh", "-c", "cat >/dev/null; echo '[1, 0]'"}}
+	if err := UpdateEmbeddings(directory, config); err != nil {
+		t.Fatal(err)
+	}
+	if notes := loadEmbeddings(directory, config).Notes; len(notes) != 2 {
+		t.Fatalf("embedded %d notes, want 2", len(notes))
+	}
+	if filepath.Dir(embeddingsFile(directory, config)) != filepath.Join(dataDir(), "embeddings") {
+		t.Errorf("embeddings kept in %s", embeddingsFile(directory, config))
+	}
+
+	if err := os.Remove(filepath.Join(directory, "Gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateEmbeddings(directory, config); err != nil {
+		t.Fatal(err)
+	}
+	notes := loadEmbeddings(directory, config).Notes
+	if _, ok := notes["Kept.md"]; len(notes) != 1 || !ok {
+		t.Errorf("embeddings for %v after Gone.md was deleted, want Kept.md only", notes)
+	}
+
+	results, err := Search(Options{Mode: ModeSemantic, Query: "kept", Path: directory, Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 1 || results.Items[0].Variables["path"] != "Kept.md" {
+		t.Errorf("semantic search found %d results, want Kept.md", len(results.Items))
+	}
+}
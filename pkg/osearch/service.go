@@ -0,0 +1,117 @@
+package osearch
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// the launchd label of the background indexer
+const ServiceLabel = "com.disser.osearch.index"
+
+func servicePlist() string {
+	return ExpandHome(filepath.Join("~/Library/LaunchAgents", ServiceLabel+".plist"))
+}
+
+// the launchd agent that runs "osearch index" at login and keeps it running,
+// with the data folder the workflow uses so the index it keeps warm is the
+// one searches read
+func serviceDefinition(program string, vaultPath string, dataFolder string, every int) string {
+	arguments := []string{program, "index", "--path", vaultPath, "--every", fmt.Sprint(every)}
+	var plist strings.Builder
+	plist.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + ServiceLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+	for _, argument := range arguments {
+		plist.WriteString("\t\t<string>" + html.EscapeString(argument) + "</string>\n")
+	}
+	plist.WriteString(`	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>alfred_workflow_data</key>
+		<string>` + html.EscapeString(dataFolder) + `</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>StandardErrorPath</key>
+	<string>` + html.EscapeString(filepath.Join(dataFolder, "index.log")) + `</string>
+</dict>
+</plist>
+`)
+	return plist.String()
+}
+
+// InstallService writes a launchd agent that keeps the index of the vault
+// at vaultPath up to date, checking every so many seconds, and loads it.
+// An empty dataFolder means the one osearch would use now.
+func InstallService(vaultPath string, dataFolder string, every int) error {
+	program, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	program, err = filepath.EvalSymlinks(program)
+	if err != nil {
+		return err
+	}
+	vaultPath, err = filepath.Abs(ExpandHome(vaultPath))
+	if err != nil {
+		return err
+	}
+	if len(dataFolder) == 0 {
+		dataFolder = dataDir()
+	}
+	dataFolder, err = filepath.Abs(ExpandHome(dataFolder))
+	if err != nil {
+		return err
+	}
+
+	plist := servicePlist()
+	if _, err := os.Stat(plist); err == nil {
+		// reinstalling: stop the old one first so launchd picks up the change
+		exec.Command("launchctl", "unload", plist).Run()
+	}
+	err = os.MkdirAll(filepath.Dir(plist), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dataFolder, 0700)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(plist, []byte(serviceDefinition(program, vaultPath, dataFolder, every)), 0644)
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("launchctl", "load", "-w", plist).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl couldn't load %s: %s %s", plist, err, out)
+	}
+	return nil
+}
+
+// UninstallService stops the background indexer and removes its agent
+func UninstallService() error {
+	plist := servicePlist()
+	if _, err := os.Stat(plist); os.IsNotExist(err) {
+		return fmt.Errorf("the indexer isn't installed")
+	}
+	out, err := exec.Command("launchctl", "unload", "-w", plist).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl couldn't unload %s: %s %s", plist, err, out)
+	}
+	return os.Remove(plist)
+}
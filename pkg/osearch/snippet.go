@@ -0,0 +1,254 @@
+package osearch
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	emphasisPattern   = regexp.MustCompile(`(\*\*|__|~~|==)(.+?)(\*\*|__|~~|==)`)
+	lineMarkerPattern = regexp.MustCompile(`^\s*(?:#{1,6}\s+|>\s*|(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?)`)
+	tagMarkupPattern  = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// strip the markdown out of a matched line so it reads cleanly in a
+// subtitle: links become their text and formatting markers go away
+func plainText(line string) string {
+	// a multiline match has the lines it spans
+	line = strings.Join(strings.Fields(line), " ")
+	line = lineMarkerPattern.ReplaceAllString(line, "")
+	line = embedPattern.ReplaceAllString(line, "$1")
+	line = imagePattern.ReplaceAllString(line, "$1")
+	line = wikilinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+		match := wikilinkPattern.FindStringSubmatch(link)
+		if len(match[2]) > 0 {
+			return match[2]
+		}
+		return match[1]
+	})
+	line = linkPattern.ReplaceAllString(line, "$1")
+	line = codePattern.ReplaceAllString(line, "$1")
+	line = emphasisPattern.ReplaceAllString(line, "$2")
+	line = italicPattern.ReplaceAllString(line, "$1$2")
+	line = tagMarkupPattern.ReplaceAllString(line, "")
+	return strings.TrimSpace(line)
+}
+
+// how many characters of a matched line a subtitle shows when --context
+// doesn't say otherwise, about as many as Alfred fits across its window
+const snippetWidth = 80
+
+// stand-ins that mark rg's match while the line is stripped of markdown, from
+// Unicode's private use area so no note will contain them
+const (
+	matchStart = '\ue000'
+	matchEnd   = '\ue001'
+)
+
+// the subtitle for a matched line: its plain text cut down to a window
+// around the match, with markers round the match if config asks for them
+func lineSnippet(line matchedLine, terms []string, config Config) string {
+	text, start, end, ok := plainSpan(line)
+	if !ok {
+		text = plainText(line.text)
+		start, end, ok = matchSpan(text, terms, config)
+	}
+	if !ok {
+		return strings.ToValidUTF8(text, "\ufffd")
+	}
+
+	var before, after string
+	if config.Context > 0 {
+		before, after = wordsAround(text[:start], text[end:], config.Context)
+	} else {
+		room := snippetWidth - graphemeCount(text[start:end])
+		before, after = charactersAround(text[:start], text[end:], room)
+	}
+	open, close := matchMarkers(config)
+	if len(open) > 0 {
+		// the other words searched for can turn up either side of the match
+		before = markTerms(before, terms, config, open, close)
+		after = markTerms(after, terms, config, open, close)
+	}
+	// a line rg couldn't decode mustn't reach Alfred as invalid UTF-8
+	return strings.ToValidUTF8(before+open+text[start:end]+close+after, "\ufffd")
+}
+
+// text with open and close round everywhere one of terms matches in it
+func markTerms(text string, terms []string, config Config, open string, close string) string {
+	var spans [][]int
+	for _, term := range terms {
+		pattern, err := regexp.Compile(termPattern(term, config))
+		if err != nil {
+			continue
+		}
+		for _, span := range pattern.FindAllStringIndex(text, -1) {
+			if span[1] > span[0] {
+				spans = append(spans, span)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i][0] < spans[j][0] || spans[i][0] == spans[j][0] && spans[i][1] > spans[j][1]
+	})
+	var marked strings.Builder
+	done := 0
+	for _, span := range spans {
+		// one term's match can lie inside another's
+		if span[0] < done {
+			continue
+		}
+		marked.WriteString(text[done:span[0]] + open + text[span[0]:span[1]] + close)
+		done = span[1]
+	}
+	marked.WriteString(text[done:])
+	return marked.String()
+}
+
+// the plain text of line along with where rg's match ended up in it, which
+// the match is marked through the stripping to find
+func plainSpan(line matchedLine) (string, int, int, bool) {
+	if line.end <= line.start || line.end > len(line.text) {
+		return "", 0, 0, false
+	}
+	marked := line.text[:line.start] + string(matchStart) + line.text[line.start:line.end] + string(matchEnd) + line.text[line.end:]
+	text := plainText(marked)
+	start := strings.IndexRune(text, matchStart)
+	end := strings.IndexRune(text, matchEnd)
+	if start < 0 || end <= start+utf8.RuneLen(matchStart) {
+		return "", 0, 0, false
+	}
+	text = text[:start] + text[start+utf8.RuneLen(matchStart):end] + text[end+utf8.RuneLen(matchEnd):]
+	return text, start, end - utf8.RuneLen(matchStart), true
+}
+
+// where the first of terms matches in line, as byte offsets
+func matchSpan(line string, terms []string, config Config) (int, int, bool) {
+	for _, term := range terms {
+		pattern, err := regexp.Compile(termPattern(term, config))
+		if err != nil {
+			continue
+		}
+		if span := pattern.FindStringIndex(line); span != nil {
+			return span[0], span[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// the text either side of a match cut down to words words each, marking
+// anything cut off with an ellipsis
+func wordsAround(before string, after string, words int) (string, string) {
+	before = strings.TrimLeftFunc(before, unicode.IsSpace)
+	after = strings.TrimRightFunc(after, unicode.IsSpace)
+	if keep := lastWords(before, words); len(keep) < len(before) {
+		before = "…" + keep
+	}
+	if keep := firstWords(after, words); len(keep) < len(after) {
+		after = keep + "…"
+	}
+	return before, after
+}
+
+// the text either side of a match cut down to room characters between
+// them, centring the match where the line allows and cutting at spaces
+// rather than through words. It counts and cuts whole graphemes, never
+// splitting an accented letter or an emoji.
+func charactersAround(before string, after string, room int) (string, string) {
+	head := graphemes(strings.TrimLeftFunc(before, unicode.IsSpace))
+	tail := graphemes(strings.TrimRightFunc(after, unicode.IsSpace))
+	if room < 0 {
+		room = 0
+	}
+	keepHead := room / 2
+	keepTail := room - keepHead
+	if len(tail) < keepTail {
+		keepHead += keepTail - len(tail)
+	}
+	if len(head) < keepHead {
+		keepTail += keepHead - len(head)
+	}
+
+	before = strings.Join(head, "")
+	if len(head) > keepHead {
+		kept := head[len(head)-keepHead:]
+		if !isSpaceGrapheme(head[len(head)-keepHead-1]) {
+			if space := indexSpace(kept); space >= 0 {
+				kept = kept[space:]
+			}
+		}
+		before = "…" + strings.TrimLeftFunc(strings.Join(kept, ""), unicode.IsSpace)
+	}
+	after = strings.Join(tail, "")
+	if len(tail) > keepTail {
+		kept := tail[:keepTail]
+		if !isSpaceGrapheme(tail[keepTail]) {
+			if space := lastIndexSpace(kept); space >= 0 {
+				kept = kept[:space]
+			}
+		}
+		after = strings.TrimRightFunc(strings.Join(kept, ""), unicode.IsSpace) + "…"
+	}
+	return before, after
+}
+
+func indexSpace(clusters []string) int {
+	for index, cluster := range clusters {
+		if isSpaceGrapheme(cluster) {
+			return index
+		}
+	}
+	return -1
+}
+
+func lastIndexSpace(clusters []string) int {
+	for index := len(clusters) - 1; index >= 0; index-- {
+		if isSpaceGrapheme(clusters[index]) {
+			return index
+		}
+	}
+	return -1
+}
+
+// what goes either side of the match in a subtitle: "markers" holds one
+// marker for both sides, or an opening and closing one separated by a space
+func matchMarkers(config Config) (string, string) {
+	markers := strings.Fields(config.Markers)
+	switch len(markers) {
+	case 1:
+		return markers[0], markers[0]
+	case 2:
+		return markers[0], markers[1]
+	}
+	return "", ""
+}
+
+// the end of s, from the start of its last n words
+func lastWords(s string, n int) string {
+	spans := tokenSpans(s)
+	if len(spans) <= n {
+		return s
+	}
+	if n == 0 {
+		return ""
+	}
+	return s[spans[len(spans)-n][0]:]
+}
+
+// the start of s, up to the end of its first n words
+func firstWords(s string, n int) string {
+	spans := tokenSpans(s)
+	if len(spans) <= n {
+		return s
+	}
+	if n == 0 {
+		return ""
+	}
+	return s[:spans[n-1][1]]
+}
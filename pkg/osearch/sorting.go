@@ -0,0 +1,66 @@
+package osearch
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the orders results can be sorted in; relevance leaves them as the
+// search ranked them
+const (
+	SortRelevance = "relevance"
+	SortModified  = "modified"
+	SortCreated   = "created"
+	SortTitle     = "title"
+	SortPath      = "path"
+)
+
+// whether order is one of the orders above
+func isSortOrder(order string) bool {
+	switch order {
+	case SortRelevance, SortModified, SortCreated, SortTitle, SortPath:
+		return true
+	}
+	return false
+}
+
+func sortKey(s string) string {
+	return strings.ToLower(foldDiacritics(s))
+}
+
+func sortResults(results []AlfredResult, order string) error {
+	switch order {
+	case SortRelevance, "":
+	case SortModified:
+		sortByModified(results)
+	case SortCreated:
+		sortByCreated(results)
+	case SortTitle:
+		sort.SliceStable(results, func(i, j int) bool {
+			return sortKey(results[i].Title) < sortKey(results[j].Title)
+		})
+	case SortPath:
+		sort.SliceStable(results, func(i, j int) bool {
+			return sortKey(results[i].Variables["path"]) < sortKey(results[j].Variables["path"])
+		})
+	default:
+		return fmt.Errorf("can't sort by %s", order)
+	}
+	return nil
+}
+
+// newest first
+func sortByModified(results []AlfredResult) {
+	modified := make(map[string]time.Time)
+	for _, result := range results {
+		if info, err := os.Stat(result.Variables["fullpath"]); err == nil {
+			modified[result.UID] = info.ModTime()
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return modified[results[i].UID].After(modified[results[j].UID])
+	})
+}
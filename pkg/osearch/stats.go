@@ -0,0 +1,170 @@
+package osearch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VaultStats sums up what's in a vault
+type VaultStats struct {
+	Notes       int
+	Attachments int
+	Words       int
+	// the longest notes by words, the notes most linked to and the most
+	// used tags, most first
+	Largest    []Tally
+	MostLinked []Tally
+	Tags       []Tally
+}
+
+// Tally is a note or tag and how many of something it has
+type Tally struct {
+	Name  string
+	Count int
+}
+
+// Stats reads every note in the vault at directory, keeping the top few of
+// each list. "backends" can pick a backend for it as "stats".
+func Stats(directory string, config Config, top int) (VaultStats, error) {
+	files, err := vaultFiles(directory, "stats", config)
+	if err != nil {
+		return VaultStats{}, err
+	}
+	resolver := newLinkResolver(files)
+
+	var stats VaultStats
+	words := make(map[string]int)
+	links := make(map[string]int)
+	tags := make(map[string]int)
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".md") {
+			stats.Attachments++
+			continue
+		}
+		stats.Notes++
+		content, err := readNote(filepath.Join(directory, file))
+		if err != nil {
+			continue
+		}
+		words[file] = wordCount(string(content))
+		stats.Words += words[file]
+		for _, link := range noteLinks(string(content)) {
+			if target, ok := resolver.resolve(link, file); ok && target != file {
+				links[target]++
+			}
+		}
+		frontmatter, body := parseFrontmatter(string(content))
+		for _, tag := range noteTags(frontmatter, body) {
+			tags[strings.ToLower(tag)]++
+		}
+	}
+
+	stats.Largest = topTallies(words, top)
+	stats.MostLinked = topTallies(links, top)
+	stats.Tags = topTallies(tags, top)
+	return stats, nil
+}
+
+// every file in the vault at directory that isn't ignored, found by the
+// backend config gives mode
+func vaultFiles(directory string, mode string, config Config) ([]string, error) {
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return nil, exitError(ExitVault, "no such directory %s", directory)
+	}
+	config.directory = directory
+	backend, err := backendFor(mode, config)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := backend.FindFiles("", config)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range found {
+		if !isIgnored(file, config.Ignore) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// the top n of counts, most first and then by name
+func topTallies(counts map[string]int, n int) []Tally {
+	var tallies []Tally
+	for name, count := range counts {
+		if count > 0 {
+			tallies = append(tallies, Tally{name, count})
+		}
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		if tallies[i].Count != tallies[j].Count {
+			return tallies[i].Count > tallies[j].Count
+		}
+		return tallies[i].Name < tallies[j].Name
+	})
+	if n > 0 && len(tallies) > n {
+		tallies = tallies[:n]
+	}
+	return tallies
+}
+
+// Results gives the stats as Alfred items: totals first, then the notes
+// in the lists, which open like any other result
+func (stats VaultStats) Results(directory string, vault string, config Config) AlfredResults {
+	valid := false
+	info := func(title string, subtitle string) AlfredResult {
+		return AlfredResult{Type: "default", Valid: &valid, Title: title, Subtitle: subtitle}
+	}
+
+	items := []AlfredResult{
+		info(fmt.Sprintf("%s %s, %s %s", groupThousands(stats.Notes), pluralWord(stats.Notes, "note"), groupThousands(stats.Attachments), pluralWord(stats.Attachments, "attachment")),
+			describeLength(stats.Words)),
+	}
+	for _, tally := range stats.Largest {
+		result := noteResult(tally.Name, directory, vault, config)
+		result.Subtitle = "Largest · " + describeLength(tally.Count)
+		items = append(items, result)
+	}
+	for _, tally := range stats.MostLinked {
+		result := noteResult(tally.Name, directory, vault, config)
+		result.Subtitle = fmt.Sprintf("Most linked · %s %s to it", groupThousands(tally.Count), pluralWord(tally.Count, "link"))
+		items = append(items, result)
+	}
+	for _, tally := range stats.Tags {
+		items = append(items, info("#"+tally.Name, fmt.Sprintf("Tag · %s %s", groupThousands(tally.Count), pluralWord(tally.Count, "note"))))
+	}
+	return AlfredResults{Items: items}
+}
+
+// WriteText writes the stats out as a report for a terminal
+func (stats VaultStats) WriteText(out io.Writer) error {
+	var report strings.Builder
+	fmt.Fprintf(&report, "%-12s %s\n", "Notes", groupThousands(stats.Notes))
+	fmt.Fprintf(&report, "%-12s %s\n", "Attachments", groupThousands(stats.Attachments))
+	fmt.Fprintf(&report, "%-12s %s\n", "Words", groupThousands(stats.Words))
+	for _, list := range []struct {
+		heading string
+		tallies []Tally
+		prefix  string
+	}{
+		{"Largest notes (words)", stats.Largest, ""},
+		{"Most linked notes (links in)", stats.MostLinked, ""},
+		{"Tags (notes)", stats.Tags, "#"},
+	} {
+		if len(list.tallies) == 0 {
+			continue
+		}
+		fmt.Fprintf(&report, "\n%s\n", list.heading)
+		for _, tally := range list.tallies {
+			fmt.Fprintf(&report, "%10s  %s%s\n", groupThousands(tally.Count), list.prefix, tally.Name)
+		}
+	}
+	_, err := io.WriteString(out, report.String())
+	return err
+}
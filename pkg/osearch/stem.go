@@ -0,0 +1,96 @@
+package osearch
+
+import "strings"
+
+// the shortest stem worth searching for; anything shorter matches too much
+const minStemLength = 3
+
+var inflections = []string{"ingly", "edly", "ing", "ed"}
+
+var derivations = []string{
+	"ational", "ization", "fulness", "iveness", "ments", "ment", "ness",
+	"ation", "ions", "ion", "ities", "ity", "ives", "ive", "able", "ible",
+	"ers", "er", "ful", "ally", "ly", "al",
+}
+
+func isVowel(r byte) bool {
+	return strings.IndexByte("aeiou", r) >= 0
+}
+
+func hasVowel(s string) bool {
+	for index := 0; index < len(s); index++ {
+		if isVowel(s[index]) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimSuffix(word string, suffix string) (string, bool) {
+	if !strings.HasSuffix(word, suffix) {
+		return word, false
+	}
+	stem := word[:len(word)-len(suffix)]
+	if len(stem) < minStemLength || !hasVowel(stem) {
+		return word, false
+	}
+	return stem, true
+}
+
+// stem cuts an English word down to a prefix its other forms share, so
+// "deploying", "deployed" and "deployment" all become "deploy". It's aimed at
+// prefix matching rather than producing dictionary words: "studies" becomes
+// "stud" so that it still matches "study".
+func stem(word string) string {
+	lower := strings.ToLower(word)
+	if len(lower) <= minStemLength || lower != strings.ToLower(foldDiacritics(word)) {
+		return lower
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "sses"):
+		lower = lower[:len(lower)-2]
+	case strings.HasSuffix(lower, "ies"):
+		lower, _ = trimSuffix(lower, "ies")
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"):
+	case strings.HasSuffix(lower, "s"):
+		lower, _ = trimSuffix(lower, "s")
+	}
+
+	for _, suffix := range inflections {
+		if stem, ok := trimSuffix(lower, suffix); ok {
+			lower = stem
+			// running → runn → run
+			last := len(lower) - 1
+			if lower[last] == lower[last-1] && !isVowel(lower[last]) && strings.IndexByte("lsz", lower[last]) < 0 {
+				lower = lower[:last]
+			}
+			break
+		}
+	}
+
+	for _, suffix := range derivations {
+		if stem, ok := trimSuffix(lower, suffix); ok {
+			lower = stem
+			break
+		}
+	}
+
+	// study, studies and studied share "stud"
+	last := len(lower) - 1
+	if len(lower) > minStemLength && (lower[last] == 'y' || lower[last] == 'i') && !isVowel(lower[last-1]) {
+		lower = lower[:last]
+	}
+	return lower
+}
+
+// the stem of word with the word's own capitals, since stem only ever cuts
+// letters off the end: withCase decides from the word as typed whether
+// to match case, and "Kubernetes" has to stay "Kubernete" to match itself
+func stemWithCase(word string) string {
+	stemmed := stem(word)
+	if lower := strings.ToLower(word); len(lower) == len(word) && strings.HasPrefix(lower, stemmed) {
+		return word[:len(stemmed)]
+	}
+	return stemmed
+}
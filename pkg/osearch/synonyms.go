@@ -0,0 +1,49 @@
+package osearch
+
+import (
+	"strings"
+)
+
+// the ways of writing word the config's synonyms allow: the word as typed
+// and whatever it's shorthand for, ignoring case. A synonym written
+// "(k8s OR kubernetes)", as they once had to be to keep finding the
+// shorthand, stands for each of its words.
+func synonymAlternatives(word string, synonyms map[string]string) []string {
+	replacement, ok := synonyms[word]
+	if !ok {
+		for shorthand, value := range synonyms {
+			if strings.EqualFold(shorthand, word) {
+				replacement, ok = value, true
+				break
+			}
+		}
+	}
+	alternatives := []string{word}
+	if !ok {
+		return alternatives
+	}
+	for _, alternative := range strings.Split(strings.Trim(strings.TrimSpace(replacement), "()"), " OR ") {
+		alternative = strings.Trim(strings.TrimSpace(alternative), `"`)
+		if len(alternative) > 0 && !strings.EqualFold(alternative, word) {
+			alternatives = append(alternatives, alternative)
+		}
+	}
+	return alternatives
+}
+
+// pattern for word that also matches what it's shorthand for, so "k8s"
+// finds notes about kubernetes as well as those that say k8s. Typing the
+// shorthand in capitals doesn't make what it stands for case sensitive.
+func synonymPattern(word string, synonyms map[string]string, pattern func(string) string) string {
+	alternatives := synonymAlternatives(word, synonyms)
+	if len(alternatives) == 1 {
+		return pattern(word)
+	}
+	for index, alternative := range alternatives {
+		alternatives[index] = pattern(alternative)
+		if index > 0 {
+			alternatives[index] = "(?i:" + alternatives[index] + ")"
+		}
+	}
+	return "(?:" + strings.Join(alternatives, "|") + ")"
+}
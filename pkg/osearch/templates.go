@@ -0,0 +1,43 @@
+package osearch
+
+// the templates whose names match, with the template's vault-relative path
+// as the argument and in the template variable, ready for whatever creates
+// the new note
+func templateResults(searchTerm string, directory string, vault string, config Config) (AlfredResults, error) {
+	folder := templatesFolder(directory)
+	if len(folder) == 0 {
+		return AlfredResults{Items: []AlfredResult{errorResult("No templates folder", "Choose one in Obsidian's Templates settings")}}, nil
+	}
+	config.Folders = []string{folder}
+	if found := config.found; found != nil {
+		config.found = func(result AlfredResult) {
+			asTemplate(&result)
+			found(result)
+		}
+	}
+	results, err := findMatchingFiles(searchTerm, directory, vault, config)
+	for index := range results.Items {
+		asTemplate(&results.Items[index])
+	}
+	return results, err
+}
+
+func asTemplate(result *AlfredResult) {
+	result.Arg = result.Variables["path"]
+	result.Variables["template"] = result.Variables["path"]
+}
+
+// leave the templates out of results, unless the search was of the
+// templates folder to begin with
+func withoutTemplates(results []AlfredResult, directory string, searched []string) []AlfredResult {
+	folder := templatesFolder(directory)
+	if len(folder) == 0 {
+		return results
+	}
+	for _, path := range searched {
+		if isIgnored(path, []string{folder}) {
+			return results
+		}
+	}
+	return withoutIgnored(results, []string{folder})
+}
@@ -0,0 +1,63 @@
+package osearch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// the title a note gives itself: a title field in its frontmatter or else
+// its first top-level heading, for notes named by an ID or a date rather
+// than what they're about. It's the title from its file name when it gives
+// none or config.FileTitles says to stick to file names.
+func displayTitle(filename string, fullPath string, config Config) string {
+	title := fileTitle(filename)
+	if config.FileTitles || !strings.HasSuffix(filename, ".md") {
+		return title
+	}
+	content, err := readNote(fullPath)
+	if err != nil {
+		return title
+	}
+	frontmatter, body := parseFrontmatter(string(content))
+	if values := frontmatter["title"]; len(values) == 1 && len(strings.TrimSpace(values[0])) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	if heading := firstHeading(body); len(heading) > 0 {
+		return heading
+	}
+	return title
+}
+
+// the title from a note's file name
+func fileTitle(filename string) string {
+	return toNFC(withoutMd(filepath.Base(filename)))
+}
+
+// the text of the first "# " heading outside code blocks, without its
+// markdown
+func firstHeading(body string) string {
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if !fenced && strings.HasPrefix(line, "# ") {
+			return plainText(line[2:])
+		}
+	}
+	return ""
+}
+
+// a subtitle that starts with the file name when the title shown isn't it,
+// so a note titled from its contents can still be told by its name
+func withFileName(title string, filename string, subtitle string) string {
+	if name := fileTitle(filename); title != name {
+		if len(subtitle) == 0 {
+			return name
+		}
+		return name + " · " + subtitle
+	}
+	return subtitle
+}
@@ -0,0 +1,50 @@
+package osearch
+
+import "unicode"
+
+// Chinese, Japanese and Korean text doesn't put spaces between words, so
+// each of its characters is treated as a word of its own
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// tokenSpans finds the words in text as [start, end) byte offsets: runs of
+// letters and digits, with every CJK character standing alone. A combining
+// mark belongs to the letter before it and starts no word of its own.
+func tokenSpans(text string) [][]int {
+	var spans [][]int
+	start := -1
+	for index, r := range text {
+		switch {
+		case isCJK(r):
+			if start >= 0 {
+				spans = append(spans, []int{start, index})
+				start = -1
+			}
+			spans = append(spans, []int{index, index + len(string(r))})
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if start < 0 {
+				start = index
+			}
+		case unicode.Is(unicode.Mn, r) && start >= 0:
+		default:
+			if start >= 0 {
+				spans = append(spans, []int{start, index})
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, []int{start, len(text)})
+	}
+	return spans
+}
+
+func tokenize(text string) []string {
+	spans := tokenSpans(text)
+	tokens := make([]string, len(spans))
+	for index, span := range spans {
+		tokens[index] = text[span[0]:span[1]]
+	}
+	return tokens
+}
@@ -0,0 +1,201 @@
+package osearch
+
+import (
+	"regexp/syntax"
+	"sort"
+	"unicode"
+)
+
+// how many letters a character class may have and still be taken for one
+// letter with its accents and cases
+const maxClassRunes = 64
+
+// whether r is a combining mark, or matches one ignoring case as the Greek
+// iota does, so that \p{Mn}* under (?i) is still only marks
+func isMark(r rune) bool {
+	if r < unicode.MaxASCII {
+		return false
+	}
+	if unicode.Is(unicode.Mn, r) {
+		return true
+	}
+	for other := unicode.SimpleFold(r); other != r; other = unicode.SimpleFold(other) {
+		if unicode.Is(unicode.Mn, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// the letter a trigram sees: without accents, and the same whatever its
+// case. ok is false for marks, which trigrams leave out.
+func trigramRune(r rune) (rune, bool) {
+	if isMark(r) {
+		return 0, false
+	}
+	r = smallestFold(r)
+	if plain, ok := foldedLetters[r]; ok {
+		r = smallestFold(plain)
+	}
+	return r, true
+}
+
+// the smallest of the letters that match r ignoring case, as (?i) matches
+// k, K and the Kelvin sign alike
+func smallestFold(r rune) rune {
+	smallest := r
+	for other := unicode.SimpleFold(r); other != r; other = unicode.SimpleFold(other) {
+		if other < smallest {
+			smallest = other
+		}
+	}
+	return smallest
+}
+
+func packTrigram(a rune, b rune, c rune) uint64 {
+	return uint64(a)<<42 | uint64(b)<<21 | uint64(c)
+}
+
+// every trigram of text, sorted
+func textTrigrams(text string) []uint64 {
+	seen := make(map[uint64]bool)
+	var window []rune
+	for _, r := range text {
+		r, ok := trigramRune(r)
+		if !ok {
+			continue
+		}
+		window = append(window, r)
+		if len(window) > 3 {
+			window = window[1:]
+		}
+		if len(window) == 3 {
+			seen[packTrigram(window[0], window[1], window[2])] = true
+		}
+	}
+	trigrams := make([]uint64, 0, len(seen))
+	for trigram := range seen {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool { return trigrams[i] < trigrams[j] })
+	return trigrams
+}
+
+// the trigrams any match of pattern is sure to contain, or none if there's
+// no telling. Literal runs in the pattern count, and so do character
+// classes that are one letter in its accented and upper case forms, as
+// foldingPattern and case insensitivity make them.
+func patternTrigrams(pattern string) []uint64 {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	var trigrams []uint64
+	for _, run := range requiredRuns(re.Simplify()) {
+		for index := 0; index+3 <= len(run); index++ {
+			trigrams = append(trigrams, packTrigram(run[index], run[index+1], run[index+2]))
+		}
+	}
+	return trigrams
+}
+
+// runs of letters every match of re has in it
+func requiredRuns(re *syntax.Regexp) [][]rune {
+	switch re.Op {
+	case syntax.OpLiteral, syntax.OpCharClass:
+		if run, ok := literalRun(re); ok {
+			return [][]rune{run}
+		}
+	case syntax.OpCapture:
+		return requiredRuns(re.Sub[0])
+	case syntax.OpPlus:
+		return requiredRuns(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredRuns(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var runs [][]rune
+		var current []rune
+		for _, sub := range re.Sub {
+			if run, ok := literalRun(sub); ok {
+				current = append(current, run...)
+				continue
+			}
+			if onlyMarks(sub) {
+				continue
+			}
+			runs = append(runs, current)
+			current = nil
+			runs = append(runs, requiredRuns(sub)...)
+		}
+		return append(runs, current)
+	}
+	return nil
+}
+
+// the letters re matches, if it only ever matches those
+func literalRun(re *syntax.Regexp) ([]rune, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		var run []rune
+		for _, r := range re.Rune {
+			if r, ok := trigramRune(r); ok {
+				run = append(run, r)
+			}
+		}
+		return run, true
+	case syntax.OpCharClass:
+		var letter rune
+		count := 0
+		for index := 0; index+1 < len(re.Rune); index += 2 {
+			for r := re.Rune[index]; r <= re.Rune[index+1]; r++ {
+				count++
+				folded, ok := trigramRune(r)
+				if !ok || count > maxClassRunes || (count > 1 && folded != letter) {
+					return nil, false
+				}
+				letter = folded
+			}
+		}
+		return []rune{letter}, count > 0
+	}
+	return nil, false
+}
+
+// whether re only matches marks, which trigrams leave out
+func onlyMarks(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpQuest, syntax.OpPlus, syntax.OpRepeat:
+		return onlyMarks(re.Sub[0])
+	case syntax.OpCharClass:
+		for index := 0; index+1 < len(re.Rune); index += 2 {
+			for r := re.Rune[index]; r <= re.Rune[index+1]; r++ {
+				if !isMark(r) {
+					return false
+				}
+			}
+		}
+		return true
+	case syntax.OpEmptyMatch:
+		return true
+	}
+	return false
+}
+
+// the ids in both sorted lists
+func intersectIds(a []int32, b []int32) []int32 {
+	var both []int32
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			both = append(both, a[0])
+			a, b = a[1:], b[1:]
+		}
+	}
+	return both
+}
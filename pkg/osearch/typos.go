@@ -0,0 +1,96 @@
+package osearch
+
+import (
+	"sort"
+	"strings"
+)
+
+// words shorter than these are left alone; one typo in a three letter word
+// matches half the vault
+const (
+	minLengthForOneTypo  = 4
+	minLengthForTwoTypos = 8
+)
+
+// wildcard stands for any single character in a word variant
+const wildcard = ""
+
+// typoTolerantWord is a regex matching word with up to maxTypos characters
+// deleted, inserted, changed or swapped with a neighbour
+func typoTolerantWord(word string, maxTypos int) string {
+	letters := []rune(foldDiacritics(word))
+	allowed := 0
+	if isCJK(letters[0]) {
+		// a single character; there's nothing to misspell
+	} else if len(letters) >= minLengthForTwoTypos {
+		allowed = 2
+	} else if len(letters) >= minLengthForOneTypo {
+		allowed = 1
+	}
+	if maxTypos < allowed {
+		allowed = maxTypos
+	}
+	if allowed == 0 {
+		return foldingPattern(word)
+	}
+
+	start := make([]string, len(letters))
+	for index, letter := range letters {
+		start[index] = string(letter)
+	}
+	variants := map[string][]string{variantPattern(start): start}
+	frontier := [][]string{start}
+	for round := 0; round < allowed; round++ {
+		var next [][]string
+		for _, variant := range frontier {
+			for _, edit := range singleEdits(variant) {
+				key := variantPattern(edit)
+				if _, ok := variants[key]; !ok {
+					variants[key] = edit
+					next = append(next, edit)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	alternatives := make([]string, 0, len(variants))
+	for key := range variants {
+		alternatives = append(alternatives, key)
+	}
+	sort.Strings(alternatives)
+	return "(?:" + strings.Join(alternatives, "|") + ")"
+}
+
+func singleEdits(letters []string) [][]string {
+	var edits [][]string
+	splice := func(prefix []string, middle []string, suffix []string) {
+		edit := make([]string, 0, len(prefix)+len(middle)+len(suffix))
+		edit = append(append(append(edit, prefix...), middle...), suffix...)
+		if len(edit) > 0 {
+			edits = append(edits, edit)
+		}
+	}
+	for index := range letters {
+		splice(letters[:index], nil, letters[index+1:])
+		splice(letters[:index], []string{wildcard}, letters[index+1:])
+		splice(letters[:index], []string{wildcard}, letters[index:])
+		if index+1 < len(letters) {
+			splice(letters[:index], []string{letters[index+1], letters[index]}, letters[index+2:])
+		}
+	}
+	splice(letters, []string{wildcard}, nil)
+	return edits
+}
+
+func variantPattern(letters []string) string {
+	var pattern strings.Builder
+	for _, letter := range letters {
+		if letter == wildcard {
+			pattern.WriteString(".")
+		} else {
+			pattern.WriteString(foldingLetterPattern([]rune(letter)[0]))
+		}
+	}
+	return pattern.String()
+}
@@ -0,0 +1,197 @@
+package osearch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// where releases are published; each has a binary per architecture,
+// named by releaseAsset, and a checksums.txt of their SHA-256 hashes as
+// sha256sum writes them
+const (
+	releasesUrl    = "https://api.github.com/repos/disser/alfred-obsidian-search/releases/latest"
+	checksumsAsset = "checksums.txt"
+)
+
+// the name of the binary a release has for this machine
+func releaseAsset() string {
+	return fmt.Sprintf("osearch-%s-%s", runtime.GOOS, runtime.GOARCH)
+}
+
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name string `json:"name"`
+	Url  string `json:"browser_download_url"`
+}
+
+var updateClient = &http.Client{Timeout: 60 * time.Second}
+
+// Update replaces the running binary with the latest release if it's
+// newer, or with force even if it isn't or this is a dev build. With
+// checkOnly it just says whether there is one.
+func Update(checkOnly bool, force bool) (string, error) {
+	release, err := latestRelease()
+	if err != nil {
+		return "", err
+	}
+	if !force {
+		if Version == "dev" {
+			return "", fmt.Errorf("this is a dev build, so there's no telling if %s is newer; use --force to install it anyway", release.TagName)
+		}
+		if compareVersions(release.TagName, Version) <= 0 {
+			return fmt.Sprintf("osearch %s is the latest", Version), nil
+		}
+	}
+	if checkOnly {
+		return fmt.Sprintf("osearch %s is out (this is %s)", release.TagName, Version), nil
+	}
+
+	binaryUrl := release.assetUrl(releaseAsset())
+	if len(binaryUrl) == 0 {
+		return "", fmt.Errorf("%s has no %s", release.TagName, releaseAsset())
+	}
+	checksumsUrl := release.assetUrl(checksumsAsset)
+	if len(checksumsUrl) == 0 {
+		return "", fmt.Errorf("%s has no %s to check %s against", release.TagName, checksumsAsset, releaseAsset())
+	}
+	checksums, err := download(checksumsUrl)
+	if err != nil {
+		return "", err
+	}
+	want, ok := checksumFor(checksums, releaseAsset())
+	if !ok {
+		return "", fmt.Errorf("%s doesn't list %s", checksumsAsset, releaseAsset())
+	}
+	binary, err := download(binaryUrl)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return "", fmt.Errorf("%s should have SHA-256 %s but has %s", releaseAsset(), want, got)
+	}
+
+	err = replaceExecutable(binary)
+	if err != nil {
+		return "", err
+	}
+	Debug("update", "from", Version, "to", release.TagName)
+	return fmt.Sprintf("updated osearch from %s to %s", Version, release.TagName), nil
+}
+
+func latestRelease() (githubRelease, error) {
+	var release githubRelease
+	content, err := download(releasesUrl)
+	if err != nil {
+		return release, err
+	}
+	err = json.Unmarshal(content, &release)
+	if err != nil {
+		return release, fmt.Errorf("could not parse the latest release: %s", err)
+	}
+	return release, nil
+}
+
+func (release githubRelease) assetUrl(name string) string {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset.Url
+		}
+	}
+	return ""
+}
+
+func download(url string) ([]byte, error) {
+	start := time.Now()
+	response, err := updateClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: %s", url, response.Status)
+	}
+	content, err := ioutil.ReadAll(response.Body)
+	Debug("download", "url", url, "took", time.Since(start), "bytes", len(content))
+	return content, err
+}
+
+// the hash checksums gives for name, in sha256sum's "hash  name" lines
+func checksumFor(checksums []byte, name string) (string, bool) {
+	lines := bufio.NewScanner(bytes.NewReader(checksums))
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// write binary next to the running executable and rename it over it, so
+// nothing ever runs half a binary
+func replaceExecutable(binary []byte) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return err
+	}
+	temporary, err := ioutil.TempFile(filepath.Dir(executable), ".osearch-update-")
+	if err != nil {
+		return fmt.Errorf("can't write next to %s: %s", executable, err)
+	}
+	defer os.Remove(temporary.Name())
+	_, err = temporary.Write(binary)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temporary.Name(), 0755)
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), executable)
+	}
+	return err
+}
+
+// compare versions like v1.10.2 part by part as numbers, so that v1.10 is
+// newer than v1.9
+func compareVersions(a string, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for index := 0; index < len(aParts) || index < len(bParts); index++ {
+		var aNumber, bNumber int
+		if index < len(aParts) {
+			aNumber, _ = strconv.Atoi(strings.SplitN(aParts[index], "-", 2)[0])
+		}
+		if index < len(bParts) {
+			bNumber, _ = strconv.Atoi(strings.SplitN(bParts[index], "-", 2)[0])
+		}
+		if aNumber != bNumber {
+			if aNumber < bNumber {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
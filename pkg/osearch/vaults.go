@@ -0,0 +1,171 @@
+package osearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// VaultLocation is a vault to search: its name as Obsidian URLs take it
+// and its folder
+type VaultLocation struct {
+	Name string
+	Path string
+}
+
+// KnownVaults lists the vaults in Obsidian's config file, in order of
+// their labels
+func KnownVaults(obsidianConfig string) ([]VaultLocation, error) {
+	content, err := ioutil.ReadFile(obsidianConfig)
+	if err != nil {
+		return nil, err
+	}
+	var known ObsidianConfig
+	err = json.Unmarshal(content, &known)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", obsidianConfig, err)
+	}
+	var vaults []VaultLocation
+	for id, vault := range known.Vaults {
+		vaults = append(vaults, VaultLocation{Name: id, Path: vault.Path})
+	}
+	sort.Slice(vaults, func(i, j int) bool {
+		return VaultLabel(vaults[i].Path) < VaultLabel(vaults[j].Path)
+	})
+	return vaults, nil
+}
+
+// PickVaults picks the vaults names asks for out of known, by label or by
+// the ID Obsidian gave them; "all" picks every one
+func PickVaults(known []VaultLocation, names []string) ([]VaultLocation, error) {
+	if len(names) == 1 && names[0] == "all" {
+		return known, nil
+	}
+	var picked []VaultLocation
+	for _, name := range names {
+		found := false
+		for _, vault := range known {
+			if vault.Name == name || strings.EqualFold(VaultLabel(vault.Path), name) {
+				picked = append(picked, vault)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Obsidian doesn't know a vault called %s", name)
+		}
+	}
+	return picked, nil
+}
+
+// SearchVaults runs the search on each of vaults in turn, labelling every
+// result with the vault it came from so the list makes sense, and each
+// result opens its note in its own vault. Grouping by vault puts them
+// under a header for each, and grouping by folder groups each vault's on
+// its own; otherwise sorting by anything but relevance sorts them all
+// together. What isn't a note, like an error or the saved searches, comes
+// first and only once. Streamed results come vault by vault, labelled but
+// not grouped. Vaults whose folder is gone, or that don't have the folder
+// --in asks for, are skipped.
+func SearchVaults(options Options, vaults []VaultLocation) (AlfredResults, error) {
+	var merged AlfredResults
+	var others []AlfredResult
+	shown := make(map[string]bool)
+	// whether result is a note, or a message not yet shown for another vault
+	showing := func(result AlfredResult) bool {
+		if len(result.Variables["path"]) > 0 {
+			return true
+		}
+		key := result.Title + "\x00" + result.Subtitle
+		if shown[key] {
+			return false
+		}
+		shown[key] = true
+		return true
+	}
+	if found := options.Found; found != nil {
+		options.Found = func(result AlfredResult) error {
+			if !showing(result) {
+				return nil
+			}
+			return found(result)
+		}
+	}
+	groupByVault := options.GroupBy == "vault"
+	groupByFolders := options.GroupBy == "folder"
+	if groupByVault || groupByFolders {
+		// grouped here rather than by each search, so groups only hold notes
+		options.GroupBy = ""
+	}
+	options.Config.ShowVault = !groupByVault || options.Found != nil
+	searched := 0
+	for _, vault := range vaults {
+		if info, err := os.Stat(ExpandHome(vault.Path)); err != nil || !info.IsDir() {
+			Debug("skip vault", "name", vault.Name, "path", vault.Path)
+			continue
+		}
+		if len(options.InFolder) > 0 && !hasFolder(ExpandHome(vault.Path), options.InFolder) {
+			Debug("skip vault", "name", vault.Name, "folder", options.InFolder)
+			continue
+		}
+		single := options
+		single.Vault = vault.Name
+		single.Path = vault.Path
+		if searched > 0 {
+			// the search only goes into the history once, and an empty
+			// search shows the history once
+			if len(options.Query) == 0 && options.Config.History > 0 {
+				break
+			}
+			single.Config.History = 0
+		}
+		results, err := Search(single)
+		if err != nil {
+			return merged, err
+		}
+		if searched == 0 {
+			merged.Cache = results.Cache
+			merged.Rerun = results.Rerun
+			merged.SkipKnowledge = results.SkipKnowledge
+		}
+		searched++
+		var notes []AlfredResult
+		for _, result := range results.Items {
+			if len(result.Variables["path"]) > 0 {
+				notes = append(notes, result)
+			} else if showing(result) {
+				others = append(others, result)
+			}
+		}
+		if groupByFolders {
+			notes = groupByFolder(AlfredResults{Items: notes}, vault.Name).Items
+		}
+		if groupByVault && len(notes) > 0 {
+			valid := false
+			merged.Items = append(merged.Items, AlfredResult{
+				Type:     "default",
+				Valid:    &valid,
+				Title:    VaultLabel(vault.Path),
+				Subtitle: fmt.Sprintf("%d in this vault", len(notes)),
+				Icon:     &AlfredIcon{Type: "fileicon", Path: ObsidianApp},
+			})
+		}
+		merged.Items = append(merged.Items, notes...)
+	}
+	if groupByVault || groupByFolders {
+		// as Search does for a grouped list
+		if options.SkipKnowledge == "auto" || options.SkipKnowledge == "" {
+			merged.SkipKnowledge = true
+		}
+	} else if len(options.Sort) > 0 && options.Sort != SortRelevance {
+		err := sortResults(merged.Items, options.Sort)
+		if err != nil {
+			return merged, err
+		}
+	}
+	merged.Items = append(others, merged.Items...)
+	return merged, nil
+}
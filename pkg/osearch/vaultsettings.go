@@ -0,0 +1,93 @@
+package osearch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// read one of the vault's own settings files in .obsidian into settings,
+// reporting whether there was one to read. Plugins that were never set up
+// have no file, which leaves settings as they were.
+func readVaultSettings(directory string, name string, settings interface{}) bool {
+	file := filepath.Join(directory, ".obsidian", name)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	err = json.Unmarshal(content, settings)
+	if err != nil {
+		log.Printf("ignoring unreadable %s: %s", file, err)
+		return false
+	}
+	return true
+}
+
+// the templates plugin's settings
+type templatesSettings struct {
+	Folder string `json:"folder"`
+}
+
+// the vault-relative folder the templates plugin takes templates from, or ""
+// if it has none
+func templatesFolder(directory string) string {
+	var settings templatesSettings
+	readVaultSettings(directory, "templates.json", &settings)
+	return strings.Trim(filepath.ToSlash(filepath.Clean("/"+settings.Folder)), "/")
+}
+
+// the daily notes plugin's settings
+type dailyNotesSettings struct {
+	Folder string `json:"folder"`
+	Format string `json:"format"`
+}
+
+// where daily notes go and how they're named, as Obsidian would make them:
+// at the top of the vault as YYYY-MM-DD unless the plugin says otherwise
+func dailyNotes(directory string) dailyNotesSettings {
+	var settings dailyNotesSettings
+	readVaultSettings(directory, "daily-notes.json", &settings)
+	settings.Folder = strings.Trim(filepath.ToSlash(filepath.Clean("/"+settings.Folder)), "/")
+	if len(strings.TrimSpace(settings.Format)) == 0 {
+		settings.Format = "YYYY-MM-DD"
+	}
+	return settings
+}
+
+// the vault-relative path of the daily note for day
+func (settings dailyNotesSettings) path(day time.Time) string {
+	return filepath.Join(settings.Folder, formatMoment(day, settings.Format)+".md")
+}
+
+// the settings from Files and links that osearch cares about
+type appSettings struct {
+	AttachmentFolderPath string `json:"attachmentFolderPath"`
+}
+
+// where new attachments go for a note in noteDir: the vault's top folder,
+// a folder of the vault, the note's own folder ("./"), or a folder inside
+// that ("./assets")
+func attachmentFolder(directory string, noteDir string) string {
+	var settings appSettings
+	readVaultSettings(directory, "app.json", &settings)
+	folder := filepath.FromSlash(settings.AttachmentFolderPath)
+	if settings.AttachmentFolderPath == "." || strings.HasPrefix(settings.AttachmentFolderPath, "./") {
+		return filepath.Join(noteDir, folder)
+	}
+	return filepath.Join(directory, folder)
+}
+
+// the one folder of the vault all attachments go in, or "" if they go
+// next to their notes or at the top of the vault
+func sharedAttachmentFolder(directory string) string {
+	var settings appSettings
+	readVaultSettings(directory, "app.json", &settings)
+	folder := settings.AttachmentFolderPath
+	if folder == "." || strings.HasPrefix(folder, "./") {
+		return ""
+	}
+	return strings.Trim(filepath.ToSlash(filepath.Clean("/"+folder)), "/")
+}
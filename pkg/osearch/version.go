@@ -0,0 +1,17 @@
+package osearch
+
+import "fmt"
+
+// what build this is, set when building with
+//
+//	go build -ldflags "-X osearch/pkg/osearch.Version=v1.2.0 -X osearch/pkg/osearch.Commit=$(git rev-parse --short HEAD) -X osearch/pkg/osearch.BuildDate=$(date -u +%Y-%m-%d)" ./cmd/osearch
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// VersionString describes this build in one line
+func VersionString() string {
+	return fmt.Sprintf("osearch %s (commit %s, built %s)", Version, Commit, BuildDate)
+}
@@ -0,0 +1,60 @@
+package osearch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// a typical silent reading speed
+const wordsPerMinute = 200
+
+// the words in a note's body, not counting its frontmatter
+func wordCount(content string) int {
+	_, body := parseFrontmatter(content)
+	return len(tokenize(body))
+}
+
+// "1,234 words · 7 min read"
+func describeLength(words int) string {
+	minutes := (words + wordsPerMinute - 1) / wordsPerMinute
+	if minutes < 1 {
+		minutes = 1
+	}
+	return fmt.Sprintf("%s %s · %d min read", groupThousands(words), pluralWord(words, "word"), minutes)
+}
+
+func pluralWord(count int, word string) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func groupThousands(n int) string {
+	digits := fmt.Sprint(n)
+	var grouped []string
+	for len(digits) > 3 {
+		grouped = append([]string{digits[len(digits)-3:]}, grouped...)
+		digits = digits[:len(digits)-3]
+	}
+	return strings.Join(append([]string{digits}, grouped...), ",")
+}
+
+// add the length of each note to its subtitle, to tell a stub from the
+// real thing
+func addWordCounts(results AlfredResults) {
+	for index, result := range results.Items {
+		if !strings.HasSuffix(result.Variables["path"], ".md") {
+			continue
+		}
+		content, err := readNote(result.Variables["fullpath"])
+		if err != nil {
+			continue
+		}
+		length := describeLength(wordCount(string(content)))
+		if len(result.Subtitle) > 0 {
+			length = result.Subtitle + " · " + length
+		}
+		results.Items[index].Subtitle = length
+	}
+}